@@ -2,21 +2,36 @@ package charts
 
 import (
 	"context"
+	_ "embed"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/Masterminds/semver/v3"
 	"gopkg.in/yaml.v3"
 	"helm.sh/helm/v3/pkg/action"
+	helmchart "helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/client-go/util/homedir"
 )
 
 var (
-	defaultResourcesPath = "./charts/charts.yaml"
-	helmDriverEnv        = "HELM_DRIVER"
+	// the charts installed on every cluster, unless overridden by a charts file
+	//go:embed charts.yaml
+	defaultChartsYAML []byte
+
+	// a user's own default charts, used instead of the built-in ones when present
+	userChartsPath = filepath.Join(".skillet", "charts.yaml")
+
+	helmDriverEnv = "HELM_DRIVER"
 )
 
 type DefaultResources struct {
@@ -24,87 +39,317 @@ type DefaultResources struct {
 }
 
 type HelmChart struct {
-	Name      string `yaml:"name"`
-	Namespace string `yaml:"namespace"`
-	Repo      string `yaml:"repo"`
-	URL       string `yaml:"url"`
-	TGZ       string `yaml:"tgz"`
+	Name      string `yaml:"name" json:"name"`
+	Namespace string `yaml:"namespace" json:"namespace"`
+	Repo      string `yaml:"repo" json:"repo,omitempty"`
+	URL       string `yaml:"url" json:"url,omitempty"`
+
+	// the chart's name in the repository at URL, an oci:// reference, or the path of a local
+	// chart directory or archive
+	Chart string `yaml:"chart" json:"chart"`
+
+	// the chart version or a semver constraint such as ^25.0.0, resolved to the newest
+	// matching version. Defaults to the newest stable version
+	Version string `yaml:"version" json:"version,omitempty"`
+
+	// values passed to the chart when it is installed, over the ones in its values files
+	Values      map[string]interface{} `yaml:"values" json:"values,omitempty"`
+	ValuesFiles []string               `yaml:"values_files" json:"valuesFiles,omitempty"`
+
+	// --set overrides given on the command line, such as persistence.enabled=true, which take
+	// precedence over all other values
+	Set []string `yaml:"-" json:"set,omitempty"`
 }
 
-// apply default resources
-func ApplyDefaultResources(ctx context.Context, kubeContext string) error {
+// returns the helm charts installed on every cluster
+func DefaultCharts(chartsFile string) ([]HelmChart, error) {
 	slog.Info("parsing chart config")
-	chartConfig, err := parseChartConfig()
+	data, dir, err := readChartConfig(chartsFile)
+	if err != nil {
+		err = fmt.Errorf("error while reading chart config: %w", err)
+		slog.Error(err.Error())
+		return nil, err
+	}
+
+	chartConfig, err := parseChartConfig(data)
 	if err != nil {
 		err = fmt.Errorf("error while parsing chart config: %w", err)
 		slog.Error(err.Error())
-		return err
+		return nil, err
 	}
 
-	slog.Info("applying helm charts")
-	for _, chart := range chartConfig.HelmCharts {
-		settings := cli.New()
+	// local charts in a charts file are relative to it
+	for i := range chartConfig.HelmCharts {
+		chartConfig.HelmCharts[i].ResolvePath(dir)
+	}
+
+	return chartConfig.HelmCharts, nil
+}
 
-		slog.Info("creating action config", "chart", chart.Name)
-		actionConfig := new(action.Configuration)
-		err := actionConfig.Init(settings.RESTClientGetter(), settings.Namespace(), os.Getenv(helmDriverEnv), log.Printf)
+// returns a chart's deployed release, or nil if it is not deployed
+func DeployedRelease(kubeContext string, chart HelmChart) (*release.Release, error) {
+	actionConfig, err := newActionConfig(kubeContext, chart.Namespace)
+	if err != nil {
+		err = fmt.Errorf("error initializing action for chart %s: %w", chart.Name, err)
+		slog.Error(err.Error())
+		return nil, err
+	}
+
+	rel, err := action.NewGet(actionConfig).Run(chart.Name)
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return nil, nil
+	} else if err != nil {
+		err = fmt.Errorf("error getting release for chart %s: %w", chart.Name, err)
+		slog.Error(err.Error())
+		return nil, err
+	}
+
+	if rel.Info.Status != release.StatusDeployed {
+		return nil, nil
+	}
+	return rel, nil
+}
+
+// describes why a deployed release no longer matches the chart, if it does not
+func (h *HelmChart) Outdated(rel *release.Release) (string, error) {
+	installed := rel.Chart.Metadata.Version
+	switch {
+	case h.IsLocal():
+		local, err := loader.Load(h.Chart)
 		if err != nil {
-			err = fmt.Errorf("error initializing action for chart %s: %w", chart.Name, err)
-			slog.Error(err.Error())
-			return err
+			return "", fmt.Errorf("error loading chart %s: %w", h.Chart, err)
 		}
-
-		slog.Info("locating chart", "chart", chart.Name)
-		client := action.NewInstall(actionConfig)
-		chartPath, err := client.LocateChart(chart.URL, settings)
+		if local.Metadata.Version != installed {
+			return fmt.Sprintf("installed version %s is not local version %s", installed, local.Metadata.Version), nil
+		}
+	case h.Version != "":
+		constraint, err := semver.NewConstraint(h.Version)
 		if err != nil {
-			err = fmt.Errorf("error locating chart %s: %w", chart.Name, err)
-			slog.Error(err.Error())
-			return err
+			return "", err
+		}
+		version, err := semver.NewVersion(installed)
+		if err != nil || !constraint.Check(version) {
+			return fmt.Sprintf("installed version %s does not match %s", installed, h.Version), nil
 		}
+	}
+
+	vals, err := h.MergedValues()
+	if err != nil {
+		return "", err
+	}
+
+	changed, err := valuesChanged(rel.Config, vals)
+	if err != nil {
+		return "", err
+	} else if changed {
+		return "values changed", nil
+	}
+
+	return "", nil
+}
+
+// installs a chart into the cluster
+func Install(ctx context.Context, kubeContext string, chart HelmChart) error {
+	settings := newSettings(kubeContext)
+
+	slog.Info("creating action config", "chart", chart.Name)
+	actionConfig, err := newChartActionConfig(kubeContext, chart, settings)
+	if err != nil {
+		return err
+	}
+
+	install := action.NewInstall(actionConfig)
+	helmChart, vals, err := loadChart(&install.ChartPathOptions, chart, settings)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("installing chart", "chart", chart.Name, "version", helmChart.Metadata.Version)
+	install.ReleaseName = chart.Name
+	install.CreateNamespace = true
+	install.Replace = true
+	install.Namespace = chart.Namespace
+	_, err = install.RunWithContext(ctx, helmChart, vals)
+	if err != nil {
+		err = fmt.Errorf("error installing chart %s: %w", chart.Name, err)
+		slog.Error(err.Error())
+		return err
+	}
+
+	slog.Info("Successfully installed chart", "chart", chart.Name)
+	return nil
+}
 
-		// TODO: Fix this part so it's not hard-coded. Currently this is a workaroud
-		chartPath = strings.TrimSuffix(chartPath, "helm-charts") + chart.TGZ
+// upgrades a chart's deployed release to the version and values the chart is configured with
+func Upgrade(ctx context.Context, kubeContext string, chart HelmChart) error {
+	settings := newSettings(kubeContext)
+
+	slog.Info("creating action config", "chart", chart.Name)
+	actionConfig, err := newChartActionConfig(kubeContext, chart, settings)
+	if err != nil {
+		return err
+	}
 
-		slog.Info("loading chart", "chart", chart.Name)
-		helmChart, err := loader.Load(chartPath)
+	upgrade := action.NewUpgrade(actionConfig)
+	helmChart, vals, err := loadChart(&upgrade.ChartPathOptions, chart, settings)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("upgrading chart", "chart", chart.Name, "version", helmChart.Metadata.Version)
+	upgrade.Namespace = chart.Namespace
+	_, err = upgrade.RunWithContext(ctx, chart.Name, helmChart, vals)
+	if err != nil {
+		err = fmt.Errorf("error upgrading chart %s: %w", chart.Name, err)
+		slog.Error(err.Error())
+		return err
+	}
+
+	slog.Info("Successfully upgraded chart", "chart", chart.Name)
+	return nil
+}
+
+// creates the action config a chart is installed or upgraded with
+func newChartActionConfig(kubeContext string, chart HelmChart, settings *cli.EnvSettings) (*action.Configuration, error) {
+	actionConfig, err := newActionConfig(kubeContext, chart.Namespace)
+	if err != nil {
+		err = fmt.Errorf("error initializing action for chart %s: %w", chart.Name, err)
+		slog.Error(err.Error())
+		return nil, err
+	}
+
+	if chart.IsOCI() {
+		actionConfig.RegistryClient, err = registry.NewClient(registry.ClientOptCredentialsFile(settings.RegistryConfig))
 		if err != nil {
-			err = fmt.Errorf("error loading chart %s: %w", chart.Name, err)
+			err = fmt.Errorf("error creating registry client for chart %s: %w", chart.Name, err)
 			slog.Error(err.Error())
-			return err
+			return nil, err
 		}
+	}
 
-		slog.Info("installing chart", "chart", chart.Name)
-		install := action.NewInstall(actionConfig)
-		install.ReleaseName = chart.Name
-		install.CreateNamespace = true
-		install.Namespace = chart.Namespace
-		var vals map[string]interface{}
-		_, err = install.RunWithContext(ctx, helmChart, vals)
-		if err != nil {
-			err = fmt.Errorf("error installing chart %s: %w", chart.Name, err)
-			slog.Error(err.Error())
-			return err
+	return actionConfig, nil
+}
+
+// locates and loads a chart, and merges the values it is installed with
+func loadChart(options *action.ChartPathOptions, chart HelmChart, settings *cli.EnvSettings) (*helmchart.Chart, map[string]interface{}, error) {
+	slog.Info("locating chart", "chart", chart.Name, "source", chart.Reference())
+	chartPath, err := locateChart(options, chart, settings)
+	if err != nil {
+		err = fmt.Errorf("error locating chart %s: %w", chart.Name, err)
+		slog.Error(err.Error())
+		return nil, nil, err
+	}
+
+	slog.Info("loading chart", "chart", chart.Name)
+	helmChart, err := loader.Load(chartPath)
+	if err != nil {
+		err = fmt.Errorf("error loading chart %s: %w", chart.Name, err)
+		slog.Error(err.Error())
+		return nil, nil, err
+	}
+
+	vals, err := chart.MergedValues()
+	if err != nil {
+		err = fmt.Errorf("error merging values of chart %s: %w", chart.Name, err)
+		slog.Error(err.Error())
+		return nil, nil, err
+	}
+
+	err = validateValues(helmChart, vals)
+	if err != nil {
+		err = fmt.Errorf("values of chart %s do not match its schema: %w", chart.Name, err)
+		slog.Error(err.Error())
+		return nil, nil, err
+	}
+
+	return helmChart, vals, nil
+}
+
+// finds a chart the same way as helm install, following the repository index to its archive
+func locateChart(options *action.ChartPathOptions, chart HelmChart, settings *cli.EnvSettings) (string, error) {
+	options.RepoURL = chart.URL
+	options.Version = chart.Version
+	return options.LocateChart(chart.Chart, settings)
+}
+
+// reports whether the chart is pulled from an OCI registry
+func (h *HelmChart) IsOCI() bool {
+	return registry.IsOCI(h.Chart)
+}
+
+// reports whether the chart is read from a local directory or archive
+func (h *HelmChart) IsLocal() bool {
+	return h.URL == "" && !h.IsOCI()
+}
+
+// resolves the paths of a local chart and of values files relative to the file they were declared in
+func (h *HelmChart) ResolvePath(dir string) {
+	if h.IsLocal() && h.Chart != "" && !filepath.IsAbs(h.Chart) {
+		h.Chart = filepath.Join(dir, h.Chart)
+	}
+
+	for i, file := range h.ValuesFiles {
+		if !filepath.IsAbs(file) && !strings.Contains(file, "://") {
+			h.ValuesFiles[i] = filepath.Join(dir, file)
 		}
+	}
+}
 
-		slog.Info("Successfully installed chart", "chart", chart.Name)
+// describes where the chart comes from and which version is wanted
+func (h *HelmChart) Reference() string {
+	reference := h.Chart
+	if h.URL != "" {
+		reference = strings.TrimSuffix(h.URL, "/") + "/" + h.Chart
 	}
 
-	slog.Info("Successfully installed all charts")
-	return nil
+	if h.Version != "" {
+		reference += "@" + h.Version
+	}
+	return reference
 }
 
-// parse chart config into struct
-func parseChartConfig() (*DefaultResources, error) {
-	data, err := os.ReadFile(defaultResourcesPath)
+func newSettings(kubeContext string) *cli.EnvSettings {
+	settings := cli.New()
+	settings.KubeContext = kubeContext
+	return settings
+}
+
+// creates a helm action config that stores releases in the given namespace
+func newActionConfig(kubeContext string, namespace string) (*action.Configuration, error) {
+	settings := newSettings(kubeContext)
+	actionConfig := new(action.Configuration)
+	err := actionConfig.Init(settings.RESTClientGetter(), namespace, os.Getenv(helmDriverEnv), log.Printf)
 	if err != nil {
-		err = fmt.Errorf("an error occurred while reading chart config: %w", err)
-		slog.Error(err.Error())
 		return nil, err
 	}
 
+	return actionConfig, nil
+}
+
+// reads the chart config and the directory it was read from
+func readChartConfig(chartsFile string) ([]byte, string, error) {
+	if chartsFile != "" {
+		data, err := os.ReadFile(chartsFile)
+		return data, filepath.Dir(chartsFile), err
+	}
+
+	if home := homedir.HomeDir(); home != "" {
+		path := filepath.Join(home, userChartsPath)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return data, filepath.Dir(path), nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, "", err
+		}
+	}
+
+	return defaultChartsYAML, "", nil
+}
+
+// parse chart config into struct
+func parseChartConfig(data []byte) (*DefaultResources, error) {
 	var config DefaultResources
-	err = yaml.Unmarshal(data, &config)
+	err := yaml.Unmarshal(data, &config)
 	if err != nil {
 		err = fmt.Errorf("an error occurred while parsing chart config: %w", err)
 		slog.Error(err.Error())
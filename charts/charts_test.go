@@ -0,0 +1,166 @@
+package charts
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+// serves a helm repository whose archives are hosted under a different path
+func newChartRepository(t *testing.T, name string, version string) *httptest.Server {
+	t.Helper()
+
+	archive, err := chartutil.Save(&chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: name, Version: version},
+	}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	archivePath := fmt.Sprintf("/releases/download/%s-%s/%s", name, version, filepath.Base(archive))
+	mux.HandleFunc("/repo/index.yaml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `apiVersion: v1
+entries:
+  %s:
+  - apiVersion: v2
+    name: %s
+    version: %s
+    urls:
+    - %s%s
+`, name, name, version, server.URL, archivePath)
+	})
+	mux.HandleFunc(archivePath, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	})
+
+	return server
+}
+
+func newTestSettings(t *testing.T) *cli.EnvSettings {
+	dir := t.TempDir()
+	settings := cli.New()
+	settings.RepositoryCache = filepath.Join(dir, "cache")
+	settings.RepositoryConfig = filepath.Join(dir, "repositories.yaml")
+	settings.RegistryConfig = filepath.Join(dir, "registry.json")
+	return settings
+}
+
+func TestLocateChart(t *testing.T) {
+	tests := []struct {
+		name  string
+		chart HelmChart
+	}{
+		{name: "ingress controller", chart: IngressController()},
+		{name: "calico", chart: CNI(CalicoCNI, CNIOptions{PodSubnets: "10.244.0.0/16"})},
+		{name: "cilium", chart: CNI(CiliumCNI, CNIOptions{PodSubnets: "10.244.0.0/16"})},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newChartRepository(t, test.chart.Chart, test.chart.Version)
+			test.chart.URL = server.URL + "/repo"
+
+			path, err := locateChart(&action.ChartPathOptions{}, test.chart, newTestSettings(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			located, err := loader.Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if located.Metadata.Name != test.chart.Chart || located.Metadata.Version != test.chart.Version {
+				t.Fatalf("located %s %s, expected %s %s", located.Metadata.Name, located.Metadata.Version, test.chart.Chart, test.chart.Version)
+			}
+		})
+	}
+}
+
+func writeChartsFile(t *testing.T, path string, name string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	data := fmt.Sprintf(`helm_charts:
+- name: %s
+  namespace: %s
+  chart: ./%s
+  values_files:
+  - values.yaml
+`, name, name, name)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func chartNames(helmCharts []HelmChart) []string {
+	names := []string{}
+	for _, chart := range helmCharts {
+		names = append(names, chart.Name)
+	}
+	return names
+}
+
+func TestDefaultCharts(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	// without a user file, the embedded list is used
+	defaults, err := DefaultCharts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := chartNames(defaults); len(names) != 2 || names[0] != "prometheus" || names[1] != "grafana" {
+		t.Fatalf("expected the embedded prometheus and grafana charts, got %v", names)
+	}
+
+	// a user file replaces the embedded list, with paths relative to it
+	userFile := filepath.Join(home, userChartsPath)
+	writeChartsFile(t, userFile, "loki")
+	defaults, err = DefaultCharts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := chartNames(defaults); len(names) != 1 || names[0] != "loki" {
+		t.Fatalf("expected the user file's loki chart, got %v", names)
+	}
+	if defaults[0].Chart != filepath.Join(filepath.Dir(userFile), "loki") || defaults[0].ValuesFiles[0] != filepath.Join(filepath.Dir(userFile), "values.yaml") {
+		t.Fatalf("expected paths relative to the user file, got %s and %v", defaults[0].Chart, defaults[0].ValuesFiles)
+	}
+
+	// --charts-file takes precedence over the user file
+	chartsFile := filepath.Join(t.TempDir(), "charts.yaml")
+	writeChartsFile(t, chartsFile, "tempo")
+	defaults, err = DefaultCharts(chartsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := chartNames(defaults); len(names) != 1 || names[0] != "tempo" {
+		t.Fatalf("expected the charts file's tempo chart, got %v", names)
+	}
+	if defaults[0].Chart != filepath.Join(filepath.Dir(chartsFile), "tempo") {
+		t.Fatalf("expected the chart path relative to the charts file, got %s", defaults[0].Chart)
+	}
+
+	if _, err := DefaultCharts(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatal("expected a missing charts file to be an error")
+	}
+}
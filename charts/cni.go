@@ -0,0 +1,97 @@
+package charts
+
+import (
+	"strings"
+)
+
+var (
+	CalicoCNI = "calico"
+	CiliumCNI = "cilium"
+
+	calicoEncapsulation = "VXLANCrossSubnet"
+	kubeAPIServerPort   = 6443
+)
+
+// network settings a CNI plugin is installed for
+type CNIOptions struct {
+	PodSubnets       string
+	NoKubeProxy      bool
+	ControlPlaneHost string
+}
+
+// returns the chart installing a CNI plugin, either calico or cilium
+func CNI(name string, options CNIOptions) HelmChart {
+	if name == CiliumCNI {
+		return cilium(options)
+	}
+	return calico(options)
+}
+
+// installs calico through the tigera operator
+func calico(options CNIOptions) HelmChart {
+	pools := []interface{}{}
+	for _, subnet := range strings.Split(options.PodSubnets, ",") {
+		pools = append(pools, map[string]interface{}{
+			"cidr":          strings.TrimSpace(subnet),
+			"encapsulation": calicoEncapsulation,
+		})
+	}
+
+	return HelmChart{
+		Name:      "calico",
+		Namespace: "tigera-operator",
+		Repo:      "projectcalico",
+		URL:       "https://docs.tigera.io/calico/charts",
+		Chart:     "tigera-operator",
+		Version:   "v3.27.2",
+		Values: map[string]interface{}{
+			"installation": map[string]interface{}{
+				"calicoNetwork": map[string]interface{}{
+					"ipPools": pools,
+				},
+			},
+		},
+	}
+}
+
+// installs cilium, which replaces kube-proxy when it is disabled
+func cilium(options CNIOptions) HelmChart {
+	ipv4, ipv6 := false, false
+	for _, subnet := range strings.Split(options.PodSubnets, ",") {
+		if strings.Contains(subnet, ":") {
+			ipv6 = true
+		} else {
+			ipv4 = true
+		}
+	}
+
+	values := map[string]interface{}{
+		"ipam": map[string]interface{}{
+			"mode": "kubernetes",
+		},
+		"image": map[string]interface{}{
+			"pullPolicy": "IfNotPresent",
+		},
+		"ipv4": map[string]interface{}{
+			"enabled": ipv4,
+		},
+		"ipv6": map[string]interface{}{
+			"enabled": ipv6,
+		},
+	}
+	if options.NoKubeProxy {
+		values["kubeProxyReplacement"] = true
+		values["k8sServiceHost"] = options.ControlPlaneHost
+		values["k8sServicePort"] = kubeAPIServerPort
+	}
+
+	return HelmChart{
+		Name:      "cilium",
+		Namespace: "kube-system",
+		Repo:      "cilium",
+		URL:       "https://helm.cilium.io",
+		Chart:     "cilium",
+		Version:   "1.15.1",
+		Values:    values,
+	}
+}
@@ -0,0 +1,49 @@
+package charts
+
+var (
+	IngressClassName  = "nginx"
+	IngressReadyLabel = "ingress-ready"
+)
+
+// the ingress controller, bound to the ingress-ready node as in the kind ingress guide
+func IngressController() HelmChart {
+	return HelmChart{
+		Name:      "ingress-nginx",
+		Namespace: "ingress-nginx",
+		Repo:      "ingress-nginx",
+		URL:       "https://kubernetes.github.io/ingress-nginx",
+		Chart:     "ingress-nginx",
+		Version:   "4.10.0",
+		Values: map[string]interface{}{
+			"controller": map[string]interface{}{
+				"hostPort": map[string]interface{}{
+					"enabled": true,
+				},
+				"nodeSelector": map[string]interface{}{
+					IngressReadyLabel: "true",
+				},
+				"tolerations": []interface{}{
+					map[string]interface{}{
+						"key":      "node-role.kubernetes.io/control-plane",
+						"operator": "Equal",
+						"effect":   "NoSchedule",
+					},
+				},
+				"service": map[string]interface{}{
+					"type": "NodePort",
+				},
+				"publishService": map[string]interface{}{
+					"enabled": false,
+				},
+				"extraArgs": map[string]interface{}{
+					"publish-status-address": "localhost",
+				},
+				"ingressClassResource": map[string]interface{}{
+					"name":    IngressClassName,
+					"default": true,
+				},
+				"watchIngressWithoutClass": true,
+			},
+		},
+	}
+}
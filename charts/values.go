@@ -0,0 +1,113 @@
+package charts
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/strvals"
+)
+
+// merges values files, inline values and --set overrides in helm's order
+func (h *HelmChart) MergedValues() (map[string]interface{}, error) {
+	options := values.Options{ValueFiles: h.ValuesFiles}
+	merged, err := options.MergeValues(getter.All(newSettings("")))
+	if err != nil {
+		return nil, err
+	}
+
+	merged = mergeValues(merged, h.Values)
+
+	for _, set := range h.Set {
+		err = strvals.ParseInto(set, merged)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing --set %s: %w", set, err)
+		}
+	}
+
+	return merged, nil
+}
+
+// checks a local chart's merged values against its schema
+func (h *HelmChart) ValidateValues() error {
+	vals, err := h.MergedValues()
+	if err != nil {
+		return err
+	}
+
+	if !h.IsLocal() {
+		return nil
+	}
+
+	helmChart, err := loader.Load(h.Chart)
+	if err != nil {
+		return fmt.Errorf("error loading chart %s: %w", h.Chart, err)
+	}
+
+	return validateValues(helmChart, vals)
+}
+
+// validates values against the chart's values.schema.json
+func validateValues(helmChart *chart.Chart, vals map[string]interface{}) error {
+	coalesced, err := chartutil.CoalesceValues(helmChart, vals)
+	if err != nil {
+		return err
+	}
+
+	return chartutil.ValidateAgainstSchema(helmChart, coalesced)
+}
+
+// merges b into a, key by key for nested maps
+func mergeValues(a map[string]interface{}, b map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(a))
+	for key, value := range a {
+		out[key] = value
+	}
+
+	for key, value := range b {
+		if nested, ok := value.(map[string]interface{}); ok {
+			if existing, ok := out[key].(map[string]interface{}); ok {
+				out[key] = mergeValues(existing, nested)
+				continue
+			}
+		}
+		out[key] = value
+	}
+
+	return out
+}
+
+// compares installed values as json, the form helm stores them in
+func valuesChanged(installed map[string]interface{}, vals map[string]interface{}) (bool, error) {
+	installedJSON, err := normalizeValues(installed)
+	if err != nil {
+		return false, err
+	}
+
+	valsJSON, err := normalizeValues(vals)
+	if err != nil {
+		return false, err
+	}
+
+	return !reflect.DeepEqual(installedJSON, valsJSON), nil
+}
+
+func normalizeValues(vals map[string]interface{}) (map[string]interface{}, error) {
+	normalized := map[string]interface{}{}
+	if len(vals) == 0 {
+		return normalized, nil
+	}
+
+	data, err := json.Marshal(vals)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
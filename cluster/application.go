@@ -1,183 +1,346 @@
 package cluster
 
 import (
-	"context"
 	"fmt"
-	"log/slog"
 
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	deploymentType  = "deployment"
+	daemonsetType   = "daemonset"
+	statefulsetType = "statefulset"
+
+	dataVolumeName   = "data"
+	defaultMountPath = "/data"
 )
 
 type Application struct {
-	Name      string `yaml:"name"`
-	Namespace string `yaml:"namespace"`
-	Replicas  int    `yaml:"replicas"`
-	Image     string `yaml:"image"`
-	Type      string `yaml:"type"`
+	Name      string              `yaml:"name"`
+	Namespace string              `yaml:"namespace"`
+	Replicas  int                 `yaml:"replicas"`
+	Image     string              `yaml:"image"`
+	Build     *Build              `yaml:"build"`
+	Type      string              `yaml:"type"`
+	Command   []string            `yaml:"command"`
+	Args      []string            `yaml:"args"`
+	Storage   *Storage            `yaml:"storage"`
+	Ports     []Port              `yaml:"ports"`
+	Ingress   *ApplicationIngress `yaml:"ingress"`
+	Resources *Resources          `yaml:"resources"`
+
+	// health checks of the application's container
+	LivenessProbe  *Probe `yaml:"liveness_probe"`
+	ReadinessProbe *Probe `yaml:"readiness_probe"`
+	StartupProbe   *Probe `yaml:"startup_probe"`
+
+	// nodes the application's pods are scheduled on
+	NodeSelector map[string]string `yaml:"node_selector"`
+	Tolerations  []Toleration      `yaml:"tolerations"`
+	Spread       bool              `yaml:"spread"`
+	AntiAffinity string            `yaml:"anti_affinity"`
+
+	// configuration injected into or mounted in the application's container
+	Env        []EnvVar    `yaml:"env"`
+	ConfigMaps []ConfigMap `yaml:"config_maps"`
+	Secrets    []Secret    `yaml:"secrets"`
+
+	// job and cronjob settings
+	Schedule     string `yaml:"schedule"`
+	BackoffLimit *int   `yaml:"backoff_limit"`
+	Completions  *int   `yaml:"completions"`
+
+	// names of jobs that must complete before the application is deployed
+	DependsOn []string `yaml:"depends_on"`
+
+	// name of the cluster the application is deployed to
+	cluster string
+
+	// directory relative paths in the application's config are resolved against
+	baseDir string
+
+	// whether the image is loaded into the nodes from the host instead of being pulled
+	loadImage bool
 }
 
-func (c *Cluster) DeployApplications(ctx context.Context, clientset *kubernetes.Clientset) error {
-	clusterConfig, err := c.parseConfig()
+// persistent storage claimed by each replica of a statefulset
+type Storage struct {
+	Size      string `yaml:"size"`
+	Class     string `yaml:"class"`
+	MountPath string `yaml:"mount_path"`
+}
+
+// returns the objects an application is made of, in the order they should be created
+func (a *Application) resources() ([]resource, error) {
+	workload, err := a.workload()
 	if err != nil {
-		err = fmt.Errorf("error parsing cluster config: %w", err)
-		slog.Error(err.Error())
-		return err
-	}
-
-	// read applications from cluster config
-	for _, app := range clusterConfig.Applications {
-		slog.Info("Deploying application", "application", app.Name)
-		switch app.Type {
-		case "deployment":
-			app.CreateDeployment(ctx, clientset)
-		case "daemonset":
-			app.CreateDaemonset(ctx, clientset)
-		default:
-			err = fmt.Errorf("application type must be one of [deployment, daemonset]")
-			slog.Error(err.Error())
-			return err
-		}
-		slog.Info("Successfully deployed application", "application", app.Name)
+		return nil, err
 	}
 
-	slog.Info("Successfully deployed all applications to cluster")
-	return nil
-}
+	container := &podTemplateOf(workload).Spec.Containers[0]
+	container.Resources, err = a.Resources.requirements()
+	if err != nil {
+		return nil, err
+	}
 
-func (a *Application) CreateDeployment(ctx context.Context, clientset *kubernetes.Clientset) error {
-	slog.Info("Creating deployment", "application", a.Name)
-	err := a.CreateNamespace(ctx, clientset)
+	resources, err := a.configResources()
 	if err != nil {
-		err = fmt.Errorf("error creating namespace for application %s: %w", a.Name, err)
-		slog.Error(err.Error())
-		return err
+		return nil, err
+	}
+
+	if len(resources) > 0 {
+		template := podTemplateOf(workload)
+		template.Annotations = map[string]string{
+			configChecksumAnnotation: configChecksum(resources),
+		}
+	}
+
+	if a.Type == statefulsetType {
+		resources = append(resources, a.headlessService())
+	}
+	if service := a.service(); service != nil {
+		resources = append(resources, service)
+	}
+	if ingress := a.ingress(); ingress != nil {
+		resources = append(resources, ingress)
 	}
 
-	slog.Info("Creating deployment", "application", a.Name)
+	return append(resources, workload), nil
+}
 
-	deploymentsClient := clientset.AppsV1().Deployments(a.Namespace)
+// returns the workload object for an application based on its type
+func (a *Application) workload() (resource, error) {
+	switch a.Type {
+	case deploymentType:
+		return a.deployment(), nil
+	case daemonsetType:
+		return a.daemonset(), nil
+	case statefulsetType:
+		return a.statefulset()
+	case jobType:
+		return a.job(), nil
+	case cronjobType:
+		return a.cronjob(), nil
+	default:
+		return nil, fmt.Errorf("application type must be one of [deployment, daemonset, statefulset, job, cronjob]")
+	}
+}
 
-	deployment := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: a.Name,
+// builds the deployment for an application
+func (a *Application) deployment() *appsv1.Deployment {
+	return &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       deploymentKind,
+			APIVersion: appsv1.SchemeGroupVersion.String(),
 		},
+		ObjectMeta: a.objectMeta(a.Name),
 		Spec: appsv1.DeploymentSpec{
 			Replicas: int32Ptr(a.Replicas),
 			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"app": a.Name,
-				},
-			},
-			Template: apiv1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"app": a.Name,
-					},
-				},
-				Spec: apiv1.PodSpec{
-					Containers: []apiv1.Container{
-						{
-							Name:  a.Name,
-							Image: a.Image,
-						},
-					},
-				},
+				MatchLabels: a.selectorLabels(),
 			},
+			Template: a.podTemplate(),
 		},
 	}
+}
 
-	_, err = deploymentsClient.Create(ctx, deployment, metav1.CreateOptions{})
-	if err != nil {
-		err = fmt.Errorf("error creating deployment %s: %w", a.Name, err)
-		slog.Error(err.Error())
-		return err
+// builds the daemonset for an application
+func (a *Application) daemonset() *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       daemonsetKind,
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: a.objectMeta(a.Name),
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: a.selectorLabels(),
+			},
+			Template: a.podTemplate(),
+		},
 	}
-
-	slog.Info("Successfully created deployment", "application", a.Name)
-	return nil
 }
 
-func (a *Application) CreateDaemonset(ctx context.Context, clientset *kubernetes.Clientset) error {
-	slog.Info("Creating daemonset", "application", a.Name)
-	err := a.CreateNamespace(ctx, clientset)
-	if err != nil {
-		err = fmt.Errorf("error creating namespace for application %s: %w", a.Name, err)
-		slog.Error(err.Error())
-		return err
+// builds the statefulset for an application, claiming a volume per replica if storage is configured
+func (a *Application) statefulset() (*appsv1.StatefulSet, error) {
+	statefulset := &appsv1.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       statefulsetKind,
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: a.objectMeta(a.Name),
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    int32Ptr(a.Replicas),
+			ServiceName: a.Name,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: a.selectorLabels(),
+			},
+			Template: a.podTemplate(),
+		},
 	}
 
-	slog.Info("Creating deployment", "application", a.Name)
+	if a.Storage == nil {
+		return statefulset, nil
+	}
 
-	daemonsetsClient := clientset.AppsV1().DaemonSets(a.Namespace)
+	size, err := k8sresource.ParseQuantity(a.Storage.Size)
+	if err != nil {
+		return nil, fmt.Errorf("storage size %s is not a valid quantity: %w", a.Storage.Size, err)
+	}
 
-	daemonset := &appsv1.DaemonSet{
+	claim := apiv1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: a.Name,
+			Name: dataVolumeName,
 		},
-		Spec: appsv1.DaemonSetSpec{
-			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"app": a.Name,
-				},
-			},
-			Template: apiv1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"app": a.Name,
-					},
-				},
-				Spec: apiv1.PodSpec{
-					Containers: []apiv1.Container{
-						{
-							Name:  a.Name,
-							Image: a.Image,
-						},
-					},
+		Spec: apiv1.PersistentVolumeClaimSpec{
+			AccessModes: []apiv1.PersistentVolumeAccessMode{apiv1.ReadWriteOnce},
+			Resources: apiv1.VolumeResourceRequirements{
+				Requests: apiv1.ResourceList{
+					apiv1.ResourceStorage: size,
 				},
 			},
 		},
 	}
+	if a.Storage.Class != "" {
+		claim.Spec.StorageClassName = &a.Storage.Class
+	}
+	statefulset.Spec.VolumeClaimTemplates = []apiv1.PersistentVolumeClaim{claim}
 
-	// _, err = deploymentsClient.Create(ctx, deployment, metav1.CreateOptions{})
-	_, err = daemonsetsClient.Create(ctx, daemonset, metav1.CreateOptions{})
-	if err != nil {
-		err = fmt.Errorf("error creating daemonset %s: %w", a.Name, err)
-		slog.Error(err.Error())
-		return err
+	mountPath := a.Storage.MountPath
+	if mountPath == "" {
+		mountPath = defaultMountPath
 	}
+	container := &statefulset.Spec.Template.Spec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, apiv1.VolumeMount{
+		Name:      dataVolumeName,
+		MountPath: mountPath,
+	})
 
-	slog.Info("Successfully created daemonset", "application", a.Name)
-	return nil
+	return statefulset, nil
 }
 
-func (a *Application) CreateNamespace(ctx context.Context, clientset *kubernetes.Clientset) error {
-	// check if namespace exists
-	_, err := clientset.CoreV1().Namespaces().Get(ctx, a.Namespace, metav1.GetOptions{})
-	if err == nil {
-		slog.Info("namespace already exists. Skipping creation")
-		return nil
+// builds the headless service that gives each statefulset replica a stable network identity
+func (a *Application) headlessService() *apiv1.Service {
+	return &apiv1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       serviceKind,
+			APIVersion: apiv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: a.objectMeta(a.Name),
+		Spec: apiv1.ServiceSpec{
+			ClusterIP: apiv1.ClusterIPNone,
+			Selector:  a.selectorLabels(),
+			Ports:     a.servicePorts(apiv1.ServiceTypeClusterIP),
+		},
 	}
+}
 
-	// create namespace
-	slog.Info("creating namespace", "namespace", a.Namespace)
-	namespaceSpec := &apiv1.Namespace{
+// builds the pod template shared by all application types
+func (a *Application) podTemplate() apiv1.PodTemplateSpec {
+	volumes, mounts := a.configVolumes()
+	return apiv1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: a.Namespace,
+			Labels: a.selectorLabels(),
+		},
+		Spec: apiv1.PodSpec{
+			Containers: []apiv1.Container{
+				{
+					Name:            a.Name,
+					Image:           a.Image,
+					ImagePullPolicy: a.imagePullPolicy(),
+					Command:         a.Command,
+					Args:            a.Args,
+					Ports:           a.containerPorts(),
+					Env:             a.env(),
+					EnvFrom:         a.envFrom(),
+					VolumeMounts:    mounts,
+
+					LivenessProbe:  a.probe(a.LivenessProbe),
+					ReadinessProbe: a.probe(a.ReadinessProbe),
+					StartupProbe:   a.probe(a.StartupProbe),
+				},
+			},
+			Volumes:                   volumes,
+			NodeSelector:              a.NodeSelector,
+			Tolerations:               a.tolerations(),
+			Affinity:                  a.affinity(),
+			TopologySpreadConstraints: a.topologySpreadConstraints(),
 		},
 	}
+}
 
-	ns, err := clientset.CoreV1().Namespaces().Create(ctx, namespaceSpec, metav1.CreateOptions{})
-	if err != nil {
-		err = fmt.Errorf("error creating namespace client for application %s: %w", a.Name, err)
-		slog.Error(err.Error())
-		return err
+// metadata for an object belonging to an application
+func (a *Application) objectMeta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: a.Namespace,
+		Labels:    a.ownerLabels(),
 	}
+}
 
-	slog.Info("Successfully created namespace", "namespace", ns.Name)
-	return nil
+func (a *Application) selectorLabels() map[string]string {
+	return map[string]string{
+		"app": a.Name,
+	}
+}
+
+// orders applications so that every application comes after the jobs it depends on
+func orderApplications(apps []Application) ([]Application, error) {
+	byName := map[string]Application{}
+	for _, app := range apps {
+		byName[app.Name] = app
+	}
+
+	ordered := []Application{}
+	visited := map[string]bool{}
+	visiting := map[string]bool{}
+	var visit func(app Application) error
+	visit = func(app Application) error {
+		if visited[app.Name] {
+			return nil
+		} else if visiting[app.Name] {
+			return fmt.Errorf("application %s has a circular dependency", app.Name)
+		}
+
+		visiting[app.Name] = true
+		for _, dependency := range app.DependsOn {
+			if dep, ok := byName[dependency]; ok {
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		visiting[app.Name] = false
+		visited[app.Name] = true
+
+		ordered = append(ordered, app)
+		return nil
+	}
+
+	for _, app := range apps {
+		if err := visit(app); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// builds a namespace owned by the given cluster
+func namespace(name string, clusterName string) *apiv1.Namespace {
+	return &apiv1.Namespace{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       namespaceKind,
+			APIVersion: apiv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: ownerLabels(clusterName),
+		},
+	}
 }
 
 func int32Ptr(i int) *int32 {
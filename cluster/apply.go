@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// reconciles an existing cluster against its configuration file
+func (c *Cluster) Apply(ctx context.Context) error {
+	slog.Info("Checking if cluster exists")
+	clusterExists, err := c.clusterExists()
+	if err != nil {
+		err = fmt.Errorf("an error occurred while checking if cluster exists: %w", err)
+		slog.Error(err.Error())
+		return err
+	} else if !clusterExists {
+		err = status.Errorf(codes.NotFound, "could not find cluster with name %v", c.Name)
+		slog.Error(err.Error())
+		return err
+	}
+
+	slog.Info("Planning changes", "cluster", c.Name)
+	plan, err := c.plan(ctx, true)
+	if err != nil {
+		err = fmt.Errorf("error planning changes: %w", err)
+		slog.Error(err.Error())
+		return err
+	}
+
+	if c.DryRun {
+		return plan.Write(os.Stdout, c.Output)
+	}
+
+	err = c.execute(ctx, plan, nil)
+	if err != nil {
+		err = fmt.Errorf("error applying changes: %w", err)
+		slog.Error(err.Error())
+		return err
+	}
+
+	slog.Info("Cluster has been successfully reconciled")
+	return nil
+}
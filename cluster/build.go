@@ -0,0 +1,365 @@
+package cluster
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	defaultDockerfile = "Dockerfile"
+	dockerignoreFile  = ".dockerignore"
+	builtImagePrefix  = "skillet/"
+	buildTagLength    = 12
+)
+
+// how an application's image is built from a Dockerfile
+type Build struct {
+	Context    string            `yaml:"context" json:"context"`
+	Dockerfile string            `yaml:"dockerfile" json:"dockerfile,omitempty"`
+	Args       map[string]string `yaml:"args" json:"args,omitempty"`
+	Target     string            `yaml:"target" json:"target,omitempty"`
+}
+
+// the build of an application's image, tagged with a hash of its inputs
+type ImageBuild struct {
+	Action      string `json:"action"`
+	Application string `json:"application"`
+	Image       string `json:"image"`
+	Build
+
+	// files of the build context, relative to it
+	files []string
+}
+
+func (b *Build) dockerfile() string {
+	if b.Dockerfile == "" {
+		return defaultDockerfile
+	}
+	return b.Dockerfile
+}
+
+// plans the builds of applications with a Dockerfile and points them at the built image
+func (c *Cluster) planBuilds(ctx context.Context, config *ClusterConfig) ([]ImageBuild, error) {
+	builds := []ImageBuild{}
+	var cli *client.Client
+	for i := range config.Applications {
+		app := &config.Applications[i]
+		if app.Build == nil {
+			continue
+		}
+
+		if cli == nil {
+			var err error
+			cli, err = newDockerClient()
+			if err != nil {
+				return nil, err
+			}
+			defer cli.Close()
+		}
+
+		build := ImageBuild{
+			Action:      createAction,
+			Application: app.Name,
+			Build:       *app.Build,
+		}
+		build.Context = app.path(app.Build.Context)
+		build.Dockerfile = app.Build.dockerfile()
+
+		var err error
+		build.files, err = contextFiles(build.Context, build.Dockerfile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading build context of %s: %w", app.Name, err)
+		}
+
+		build.Image, err = build.tag(app.imageRepository())
+		if err != nil {
+			return nil, fmt.Errorf("error hashing build context of %s: %w", app.Name, err)
+		}
+
+		_, _, err = cli.ImageInspectWithRaw(ctx, build.Image)
+		if err == nil {
+			build.Action = unchangedAction
+		} else if !client.IsErrNotFound(err) {
+			return nil, fmt.Errorf("error finding image %s: %w", build.Image, err)
+		}
+
+		app.Image = build.Image
+		builds = append(builds, build)
+	}
+
+	return builds, nil
+}
+
+// the repository a built image is tagged in
+func (a *Application) imageRepository() string {
+	if a.Image != "" {
+		if named, err := reference.ParseNormalizedNamed(a.Image); err == nil {
+			return reference.FamiliarName(named)
+		}
+	}
+	return builtImagePrefix + a.Name
+}
+
+// lists the build context files not excluded by .dockerignore
+func contextFiles(dir string, dockerfile string) ([]string, error) {
+	patterns := []string{}
+	data, err := os.Open(filepath.Join(dir, dockerignoreFile))
+	if err == nil {
+		patterns, err = ignorefile.ReadAll(data)
+		data.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", dockerignoreFile, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	matcher, err := patternmatcher.New(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", dockerignoreFile, err)
+	}
+
+	files := []string{}
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		ignored, err := matcher.MatchesOrParentMatches(rel)
+		if err != nil {
+			return err
+		}
+		if ignored && rel != filepath.ToSlash(dockerfile) {
+			// skip directories unless an exclusion pattern brings part of them back
+			if entry.IsDir() && !matcher.Exclusions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// tags the build with a hash of its Dockerfile, arguments, target and context files
+func (b *ImageBuild) tag(repository string) (string, error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "dockerfile=%s\ntarget=%s\n", b.Dockerfile, b.Target)
+
+	args := make([]string, 0, len(b.Args))
+	for name := range b.Args {
+		args = append(args, name)
+	}
+	sort.Strings(args)
+	for _, name := range args {
+		fmt.Fprintf(hash, "arg=%s=%s\n", name, b.Args[name])
+	}
+
+	for _, file := range b.files {
+		path := filepath.Join(b.Context, filepath.FromSlash(file))
+		info, err := os.Lstat(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "file=%s %s\n", file, info.Mode())
+
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(hash, "link=%s\n", target)
+		case info.Mode().IsRegular():
+			f, err := os.Open(path)
+			if err != nil {
+				return "", err
+			}
+			_, err = io.Copy(hash, f)
+			f.Close()
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return repository + ":" + hex.EncodeToString(hash.Sum(nil))[:buildTagLength], nil
+}
+
+// builds every image the plan needs that does not already exist on the host
+func buildImages(ctx context.Context, builds []ImageBuild) error {
+	cli, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	for _, build := range builds {
+		if build.Action != createAction {
+			slog.Info("Image is up to date. Skipping build", "application", build.Application, "image", build.Image)
+			continue
+		}
+
+		slog.Info("Building image", "application", build.Application, "image", build.Image)
+		err := build.run(ctx, cli)
+		if err != nil {
+			return fmt.Errorf("error building image of %s: %w", build.Application, err)
+		}
+	}
+
+	return nil
+}
+
+// sends the build context to docker and streams the build output to stderr
+func (b *ImageBuild) run(ctx context.Context, cli *client.Client) error {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(b.writeContext(writer))
+	}()
+	defer reader.Close()
+
+	args := map[string]*string{}
+	for name, value := range b.Args {
+		value := value
+		args[name] = &value
+	}
+
+	response, err := cli.ImageBuild(ctx, reader, types.ImageBuildOptions{
+		Tags:        []string{b.Image},
+		Dockerfile:  filepath.ToSlash(b.Dockerfile),
+		BuildArgs:   args,
+		Target:      b.Target,
+		Remove:      true,
+		ForceRemove: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	return jsonmessage.DisplayJSONMessagesStream(response.Body, os.Stderr, os.Stderr.Fd(), false, nil)
+}
+
+// writes the build context as a tar archive
+func (b *ImageBuild) writeContext(w io.Writer) error {
+	archive := tar.NewWriter(w)
+	for _, file := range b.files {
+		path := filepath.Join(b.Context, filepath.FromSlash(file))
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			link, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = file
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		err = archive.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(archive, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return archive.Close()
+}
+
+func validateBuild(app Application) error {
+	if app.Build == nil {
+		return nil
+	}
+
+	if app.Build.Context == "" {
+		return status.Errorf(codes.FailedPrecondition, "build context must not be empty")
+	}
+
+	dir := app.path(app.Build.Context)
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return status.Errorf(codes.FailedPrecondition, "build context %s must be a directory", dir)
+	}
+
+	dockerfile := filepath.Clean(app.Build.dockerfile())
+	if filepath.IsAbs(dockerfile) || dockerfile == ".." || strings.HasPrefix(dockerfile, ".."+string(filepath.Separator)) {
+		return status.Errorf(codes.FailedPrecondition, "dockerfile %s must be a path inside the build context", app.Build.Dockerfile)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, dockerfile)); err != nil {
+		return status.Errorf(codes.FailedPrecondition, "dockerfile %s does not exist in build context %s", dockerfile, dir)
+	}
+
+	for name := range app.Build.Args {
+		if name == "" {
+			return status.Errorf(codes.FailedPrecondition, "build arg names must not be empty")
+		}
+	}
+
+	// the tag comes from the build's contents
+	if app.Image != "" {
+		named, err := reference.ParseNormalizedNamed(app.Image)
+		if err != nil {
+			return status.Errorf(codes.FailedPrecondition, "image %s is not a valid image name: %v", app.Image, err)
+		} else if _, ok := named.(reference.Digested); ok {
+			return status.Errorf(codes.FailedPrecondition, "image %s of a built application must not have a digest", app.Image)
+		}
+	}
+
+	return nil
+}
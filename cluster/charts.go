@@ -0,0 +1,220 @@
+package cluster
+
+import (
+	"fmt"
+	"os"
+	"skillet/skillet-kind/charts"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// per-cluster changes to the default helm releases
+type ChartsConfig struct {
+	Disable []string `yaml:"disable"`
+
+	// a release named after a default one replaces it
+	Releases []charts.HelmChart `yaml:"releases"`
+}
+
+// applies the cluster's changes to the default releases
+func (c *ChartsConfig) releases(defaults []charts.HelmChart) []charts.HelmChart {
+	if c == nil {
+		return defaults
+	}
+
+	disabled := map[string]bool{}
+	for _, name := range c.Disable {
+		disabled[name] = true
+	}
+
+	overrides := map[string]charts.HelmChart{}
+	for _, chart := range c.Releases {
+		overrides[chart.Name] = chart
+	}
+
+	releases := []charts.HelmChart{}
+	for _, chart := range defaults {
+		if disabled[chart.Name] {
+			continue
+		}
+
+		if override, ok := overrides[chart.Name]; ok {
+			chart = override
+			delete(overrides, chart.Name)
+		}
+		releases = append(releases, chart)
+	}
+
+	for _, chart := range c.Releases {
+		if _, ok := overrides[chart.Name]; ok {
+			releases = append(releases, chart)
+		}
+	}
+
+	return releases
+}
+
+// the releases installed on the cluster, other than its CNI plugin
+func (c *Cluster) releases(config *ClusterConfig) ([]charts.HelmChart, error) {
+	defaults, err := charts.DefaultCharts(c.ChartsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	releases := config.Charts.releases(defaults)
+	if config.Ingress.enabled() {
+		releases = append(releases, charts.IngressController())
+	}
+
+	for i := range releases {
+		releases[i].Set = c.setValues(releases[i].Name)
+	}
+
+	return releases, nil
+}
+
+// the chart of the cluster's CNI plugin, if skillet installs one
+func (c *Cluster) cniRelease(config *ClusterConfig) *charts.HelmChart {
+	cni := config.Networking.cniChart(c.Name)
+	if cni != nil {
+		cni.Set = c.setValues(cni.Name)
+	}
+	return cni
+}
+
+// the --set overrides given for a release
+func (c *Cluster) setValues(release string) []string {
+	values := []string{}
+	for _, value := range c.SetValues {
+		if name, set, ok := splitSetValue(value); ok && name == release {
+			values = append(values, set)
+		}
+	}
+	return values
+}
+
+// splits a RELEASE:KEY=VALUE override into the release and KEY=VALUE
+func splitSetValue(value string) (string, string, bool) {
+	release, set, ok := strings.Cut(value, ":")
+	if !ok || release == "" || strings.Contains(release, "=") {
+		return "", "", false
+	}
+	return release, set, true
+}
+
+func (c *Cluster) validateCharts(config *ClusterConfig) error {
+	defaults, err := charts.DefaultCharts(c.ChartsFile)
+	if err != nil {
+		return err
+	}
+
+	names := map[string]bool{}
+	for i, chart := range defaults {
+		if err := validateChart(chart); err != nil {
+			return fmt.Errorf("default chart %d is invalid: %w", i, err)
+		}
+		names[chart.Name] = true
+	}
+
+	if config.Charts != nil {
+		if err := validateChartsConfig(config, names); err != nil {
+			return err
+		}
+	}
+
+	releases, err := c.releases(config)
+	if err != nil {
+		return err
+	}
+	if cni := c.cniRelease(config); cni != nil {
+		releases = append(releases, *cni)
+	}
+
+	installed := map[string]bool{}
+	for _, chart := range releases {
+		installed[chart.Name] = true
+	}
+
+	for _, value := range c.SetValues {
+		release, _, ok := splitSetValue(value)
+		if !ok {
+			return status.Errorf(codes.FailedPrecondition, "--set %s must be of the form RELEASE:KEY=VALUE", value)
+		} else if !installed[release] {
+			return status.Errorf(codes.FailedPrecondition, "--set %s names release %s, which is not installed on the cluster", value, release)
+		}
+	}
+
+	for _, chart := range releases {
+		if err := chart.ValidateValues(); err != nil {
+			return status.Errorf(codes.FailedPrecondition, "values of chart %s are invalid: %v", chart.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func validateChartsConfig(config *ClusterConfig, names map[string]bool) error {
+	for _, name := range config.Charts.Disable {
+		if !names[name] {
+			return status.Errorf(codes.FailedPrecondition, "cannot disable chart %s, which is not a default chart", name)
+		}
+	}
+
+	declared := map[string]bool{}
+	for i, chart := range config.Charts.Releases {
+		if err := validateChart(chart); err != nil {
+			return fmt.Errorf("chart %d is invalid: %w", i, err)
+		}
+
+		if declared[chart.Name] {
+			return status.Errorf(codes.FailedPrecondition, "chart %s is declared more than once", chart.Name)
+		}
+		declared[chart.Name] = true
+
+		if config.Ingress.enabled() && chart.Name == charts.IngressController().Name {
+			return status.Errorf(codes.FailedPrecondition, "chart %s is reserved for the ingress controller", chart.Name)
+		}
+
+		if cni := config.Networking.cniChart(config.Name); cni != nil && chart.Name == cni.Name {
+			return status.Errorf(codes.FailedPrecondition, "chart %s is reserved for the cni", chart.Name)
+		}
+	}
+
+	return nil
+}
+
+func validateChart(chart charts.HelmChart) error {
+	if chart.Name == "" {
+		return status.Errorf(codes.FailedPrecondition, "chart name must not be empty")
+	}
+
+	if chart.Namespace == "" {
+		return status.Errorf(codes.FailedPrecondition, "namespace of chart %s must not be empty", chart.Name)
+	}
+
+	if chart.Chart == "" {
+		return status.Errorf(codes.FailedPrecondition, "chart %s must name a chart in its repository, an oci:// reference, or a local chart", chart.Name)
+	}
+
+	switch {
+	case chart.IsOCI() && chart.URL != "":
+		return status.Errorf(codes.FailedPrecondition, "chart %s is an oci:// reference, so it must not have a repository url", chart.Name)
+	case chart.IsLocal() && chart.Version != "":
+		return status.Errorf(codes.FailedPrecondition, "chart %s is a local chart, so it must not have a version", chart.Name)
+	case chart.IsLocal():
+		if _, err := os.Stat(chart.Chart); err != nil {
+			return status.Errorf(codes.FailedPrecondition, "local chart %s of %s does not exist. Set url to install it from a repository", chart.Chart, chart.Name)
+		}
+	}
+
+	if chart.Version != "" {
+		if _, err := semver.NewConstraint(chart.Version); err != nil {
+			return status.Errorf(codes.FailedPrecondition, "version %s of chart %s is not a valid version or constraint: %v", chart.Version, chart.Name, err)
+		}
+	}
+
+	return nil
+}
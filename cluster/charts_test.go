@@ -0,0 +1,72 @@
+package cluster
+
+import (
+	"skillet/skillet-kind/charts"
+	"slices"
+	"testing"
+)
+
+func TestChartsConfigReleases(t *testing.T) {
+	defaults := []charts.HelmChart{
+		{Name: "prometheus", Namespace: "prometheus", Chart: "prometheus", Version: "25.13.0"},
+		{Name: "grafana", Namespace: "grafana", Chart: "grafana", Version: "7.3.0"},
+	}
+
+	tests := []struct {
+		name     string
+		config   *ChartsConfig
+		releases []string
+		versions []string
+	}{
+		{
+			name:     "no overrides",
+			releases: []string{"prometheus", "grafana"},
+			versions: []string{"25.13.0", "7.3.0"},
+		},
+		{
+			name:     "disable",
+			config:   &ChartsConfig{Disable: []string{"prometheus"}},
+			releases: []string{"grafana"},
+			versions: []string{"7.3.0"},
+		},
+		{
+			name:     "replace in place",
+			config:   &ChartsConfig{Releases: []charts.HelmChart{{Name: "prometheus", Chart: "prometheus", Version: "25.14.0"}}},
+			releases: []string{"prometheus", "grafana"},
+			versions: []string{"25.14.0", "7.3.0"},
+		},
+		{
+			name:     "add after defaults",
+			config:   &ChartsConfig{Releases: []charts.HelmChart{{Name: "loki", Chart: "loki", Version: "5.43.0"}}},
+			releases: []string{"prometheus", "grafana", "loki"},
+			versions: []string{"25.13.0", "7.3.0", "5.43.0"},
+		},
+		{
+			name: "disable, replace, and add",
+			config: &ChartsConfig{
+				Disable: []string{"grafana"},
+				Releases: []charts.HelmChart{
+					{Name: "loki", Chart: "loki", Version: "5.43.0"},
+					{Name: "prometheus", Chart: "prometheus", Version: "25.14.0"},
+				},
+			},
+			releases: []string{"prometheus", "loki"},
+			versions: []string{"25.14.0", "5.43.0"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			releases := test.config.releases(slices.Clone(defaults))
+
+			names, versions := []string{}, []string{}
+			for _, release := range releases {
+				names = append(names, release.Name)
+				versions = append(versions, release.Version)
+			}
+			if !slices.Equal(names, test.releases) || !slices.Equal(versions, test.versions) {
+				t.Fatalf("expected releases %v at %v, got %v at %v", test.releases, test.versions, names, versions)
+			}
+		})
+	}
+}
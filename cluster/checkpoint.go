@@ -0,0 +1,193 @@
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	checkpointFile = "checkpoint.json"
+)
+
+// the completed phases of a cluster creation, so a failed one can be resumed
+type checkpoint struct {
+	Cluster    string   `json:"cluster"`
+	ConfigHash string   `json:"configHash"`
+	Completed  []string `json:"completed"`
+
+	path string
+}
+
+// starts a new checkpoint for creating the cluster from its current config
+func (c *Cluster) newCheckpoint() (*checkpoint, error) {
+	path, err := c.checkpointPath()
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := c.configFileHash()
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &checkpoint{
+		Cluster:    c.Name,
+		ConfigHash: hash,
+		Completed:  []string{},
+		path:       path,
+	}
+	return cp, cp.save()
+}
+
+// loads the checkpoint of a failed creation of the cluster
+func (c *Cluster) loadCheckpoint() (*checkpoint, error) {
+	path, err := c.checkpointPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, status.Errorf(codes.NotFound, "cluster %s has no failed creation to resume", c.Name)
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading checkpoint: %w", err)
+	}
+
+	cp := &checkpoint{path: path}
+	err = json.Unmarshal(data, cp)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing checkpoint %s: %w", path, err)
+	}
+
+	hash, err := c.configFileHash()
+	if err != nil {
+		return nil, err
+	} else if hash != cp.ConfigHash {
+		return nil, status.Errorf(codes.FailedPrecondition, "the configuration of cluster %s changed since its creation failed. Delete the cluster and create it again", c.Name)
+	}
+
+	return cp, nil
+}
+
+func (c *Cluster) checkpointPath() (string, error) {
+	dir, err := c.stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, checkpointFile), nil
+}
+
+// hash of the cluster's config file
+func (c *Cluster) configFileHash() (string, error) {
+	if c.ConfigFile == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(c.ConfigFile)
+	if err != nil {
+		return "", fmt.Errorf("error reading cluster config: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (cp *checkpoint) done(phase string) bool {
+	return cp != nil && slices.Contains(cp.Completed, phase)
+}
+
+// records a completed phase
+func (cp *checkpoint) complete(phase string) error {
+	if cp == nil {
+		return nil
+	}
+
+	cp.Completed = append(cp.Completed, phase)
+	return cp.save()
+}
+
+func (cp *checkpoint) save() error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(cp.path), 0o700)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(cp.path, data, 0o600)
+}
+
+func (cp *checkpoint) remove() error {
+	if cp == nil {
+		return nil
+	}
+
+	err := os.Remove(cp.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// checks the live cluster still holds what the completed phases created
+func (cp *checkpoint) verify(plan *Plan) error {
+	mismatch := func(phase string, what string) error {
+		return status.Errorf(codes.FailedPrecondition, "the live cluster no longer matches the checkpoint: phase %s completed but %s. Delete the cluster and create it again", phase, what)
+	}
+
+	if cp.done(buildPhase) {
+		for _, build := range plan.Builds {
+			if build.Action != unchangedAction {
+				return mismatch(buildPhase, fmt.Sprintf("image %s of %s is not built", build.Image, build.Application))
+			}
+		}
+	}
+
+	if cp.done(cniPhase) && plan.CNI != nil && plan.CNI.Action != unchangedAction {
+		return mismatch(cniPhase, releaseMismatch(*plan.CNI))
+	}
+
+	if cp.done(namespacesPhase) {
+		for _, change := range plan.Namespaces {
+			if change.Action != unchangedAction {
+				return mismatch(namespacesPhase, fmt.Sprintf("namespace %s needs to be %sd", change.Name, change.Action))
+			}
+		}
+	}
+
+	if cp.done(applicationsPhase) {
+		for _, change := range plan.Resources {
+			if change.Action != unchangedAction && change.Action != waitAction {
+				return mismatch(applicationsPhase, fmt.Sprintf("%s %s/%s needs to be %sd", change.Kind, change.Namespace, change.Name, change.Action))
+			}
+		}
+	}
+
+	for _, release := range plan.HelmReleases {
+		phase := chartPhase(release.HelmChart)
+		if cp.done(phase) && release.Action != unchangedAction {
+			return mismatch(phase, releaseMismatch(release))
+		}
+	}
+
+	return nil
+}
+
+func releaseMismatch(change ReleaseChange) string {
+	if change.Action == updateAction {
+		return fmt.Sprintf("release %s needs to be upgraded: %s", change.Name, change.Reason)
+	}
+	return fmt.Sprintf("release %s is not installed", change.Name)
+}
@@ -1,14 +1,16 @@
 package cluster
 
 import (
-	"flag"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"skillet/skillet-kind/charts"
+	"time"
 
 	"gopkg.in/yaml.v3"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/client-go/util/homedir"
@@ -20,32 +22,98 @@ var (
 	kindApiVersion   = "kind.x-k8s.io/v1alpha4"
 	controlPlaneRole = "control-plane"
 	workerRole       = "worker"
+	stateDirName     = ".skillet"
 )
 
 type Cluster struct {
 	Name       string
 	ConfigFile string
+
+	// delete skillet-owned applications that are no longer in the config when applying
+	Prune bool
+
+	// print the plan instead of executing it, in the given output format
+	DryRun bool
+	Output string
+
+	// how long to wait for applications to roll out and jobs to complete
+	Timeout time.Duration
+
+	// keep a cluster whose creation failed instead of deleting it
+	KeepOnFailure bool
+
+	// continue a failed creation from its checkpoint
+	Resume bool
+
+	// remove the cluster's local registry container when deleting it, unless other clusters still
+	// use it
+	RemoveRegistry bool
+
+	// remove the registry container even if other clusters still use it
+	ForceRemoveRegistry bool
+
+	// file listing the default helm charts, instead of the user's or the built-in ones
+	ChartsFile string
+
+	// helm values set on the command line, each of the form RELEASE:KEY=VALUE
+	SetValues []string
 }
 
 type ClusterConfig struct {
-	Name         string        `yaml:"name"`
-	Nodes        NodesConfig   `yaml:"nodes"`
-	Applications []Application `yaml:"applications"`
+	Name              string            `yaml:"name"`
+	KubernetesVersion string            `yaml:"kubernetes_version"`
+	Nodes             NodesConfig       `yaml:"nodes"`
+	Applications      []Application     `yaml:"applications"`
+	Ingress           *IngressConfig    `yaml:"ingress"`
+	Networking        *NetworkingConfig `yaml:"networking"`
+	Registry          *RegistryConfig   `yaml:"registry"`
+	Charts            *ChartsConfig     `yaml:"charts"`
+
+	// settings passed through to kind as they are
+	KubeadmConfigPatches    []string          `yaml:"kubeadm_config_patches"`
+	ContainerdConfigPatches []string          `yaml:"containerd_config_patches"`
+	FeatureGates            map[string]bool   `yaml:"feature_gates"`
+	RuntimeConfig           map[string]string `yaml:"runtime_config"`
 }
 
+// the nodes of a cluster, either counted by role or listed one by one
 type NodesConfig struct {
-	ControlPlane int `yaml:"control_plane"`
-	Worker       int `yaml:"worker"`
+	ControlPlane int    `yaml:"control_plane"`
+	Worker       int    `yaml:"worker"`
+	List         []Node `yaml:"list"`
 }
 
 type KindConfig struct {
-	Kind       string     `yaml:"kind"`
-	ApiVersion string     `yaml:"apiVersion"`
-	Nodes      []KindNode `yaml:"nodes"`
+	Kind       string          `yaml:"kind"`
+	ApiVersion string          `yaml:"apiVersion"`
+	Networking *KindNetworking `yaml:"networking,omitempty"`
+	Nodes      []KindNode      `yaml:"nodes"`
+
+	KubeadmConfigPatches    []string          `yaml:"kubeadmConfigPatches,omitempty"`
+	ContainerdConfigPatches []string          `yaml:"containerdConfigPatches,omitempty"`
+	FeatureGates            map[string]bool   `yaml:"featureGates,omitempty"`
+	RuntimeConfig           map[string]string `yaml:"runtimeConfig,omitempty"`
 }
 
 type KindNode struct {
-	Role string `yaml:"role"`
+	Role                 string            `yaml:"role"`
+	Image                string            `yaml:"image,omitempty"`
+	Labels               map[string]string `yaml:"labels,omitempty"`
+	KubeadmConfigPatches []string          `yaml:"kubeadmConfigPatches,omitempty"`
+	ExtraMounts          []KindMount       `yaml:"extraMounts,omitempty"`
+	ExtraPortMappings    []PortMapping     `yaml:"extraPortMappings,omitempty"`
+}
+
+type KindMount struct {
+	HostPath      string `yaml:"hostPath"`
+	ContainerPath string `yaml:"containerPath"`
+	ReadOnly      bool   `yaml:"readOnly,omitempty"`
+}
+
+type PortMapping struct {
+	ContainerPort int    `yaml:"containerPort"`
+	HostPort      int    `yaml:"hostPort"`
+	Protocol      string `yaml:"protocol,omitempty"`
 }
 
 func NewCluster(name string, config string) Cluster {
@@ -57,15 +125,8 @@ func NewCluster(name string, config string) Cluster {
 
 // checks if a cluster exists or not
 func (c *Cluster) clusterExists() (bool, error) {
-	if c.Name == "" {
-		clusterConfig, err := c.parseConfig()
-		if err != nil {
-			err = fmt.Errorf("error occurred while parsing config: %w", err)
-			slog.Error(err.Error())
-			return false, err
-		}
-
-		c.Name = clusterConfig.Name
+	if _, err := c.resolveName(); err != nil {
+		return false, err
 	}
 
 	kubeContext := kindPrefix + c.Name
@@ -86,6 +147,32 @@ func (c *Cluster) clusterExists() (bool, error) {
 	return false, nil
 }
 
+// fills in the cluster name from the config file if it was not given
+func (c *Cluster) resolveName() (string, error) {
+	if c.Name == "" {
+		clusterConfig, err := c.parseConfig()
+		if err != nil {
+			err = fmt.Errorf("error occurred while parsing config: %w", err)
+			slog.Error(err.Error())
+			return "", err
+		}
+
+		c.Name = clusterConfig.Name
+	}
+
+	return c.Name, nil
+}
+
+// returns the directory skillet keeps a cluster's local state in, such as its certificate authority
+func (c *Cluster) stateDir() (string, error) {
+	home := homedir.HomeDir()
+	if home == "" {
+		return "", fmt.Errorf("could not find home directory for cluster state")
+	}
+
+	return filepath.Join(home, stateDirName, "clusters", c.Name), nil
+}
+
 func (c *Cluster) generateKindConfig() (string, error) {
 	// generate the struct
 	clusterConfig, err := c.parseConfig()
@@ -97,22 +184,46 @@ func (c *Cluster) generateKindConfig() (string, error) {
 
 	// add the nodes
 	kindNodes := []KindNode{}
-	for i := 0; i < clusterConfig.Nodes.ControlPlane; i++ {
-		kindNodes = append(kindNodes, KindNode{
-			Role: controlPlaneRole,
-		})
+	for _, node := range clusterConfig.Nodes.nodes() {
+		kindNode, err := clusterConfig.kindNode(node, filepath.Dir(c.ConfigFile))
+		if err != nil {
+			err = fmt.Errorf("error generating kind config: %w", err)
+			slog.Error(err.Error())
+			return "", err
+		}
+		kindNodes = append(kindNodes, kindNode)
 	}
-	for i := 0; i < clusterConfig.Nodes.Worker; i++ {
-		kindNodes = append(kindNodes, KindNode{
-			Role: workerRole,
-		})
+
+	// forward host ports of the ingress controller and applications through the first control plane node
+	for i := range kindNodes {
+		if kindNodes[i].Role != controlPlaneRole {
+			continue
+		}
+
+		mappings := append(clusterConfig.Ingress.portMappings(), hostPortMappings(clusterConfig.Applications)...)
+		kindNodes[i].ExtraPortMappings = append(mappings, kindNodes[i].ExtraPortMappings...)
+		if clusterConfig.Ingress.enabled() {
+			labels := map[string]string{}
+			for key, value := range kindNodes[i].Labels {
+				labels[key] = value
+			}
+			labels[charts.IngressReadyLabel] = "true"
+			kindNodes[i].Labels = labels
+		}
+		break
 	}
 
 	// put the info into a KindConfig
 	kindConfig := &KindConfig{
 		Kind:       kindConfigKind,
 		ApiVersion: kindApiVersion,
+		Networking: clusterConfig.Networking.kindNetworking(),
 		Nodes:      kindNodes,
+
+		KubeadmConfigPatches:    clusterConfig.KubeadmConfigPatches,
+		ContainerdConfigPatches: append(clusterConfig.Registry.containerdPatches(), clusterConfig.ContainerdConfigPatches...),
+		FeatureGates:            clusterConfig.FeatureGates,
+		RuntimeConfig:           clusterConfig.RuntimeConfig,
 	}
 
 	// Marshal the KindConfig
@@ -143,18 +254,27 @@ func (c *Cluster) parseConfig() (*ClusterConfig, error) {
 		return nil, err
 	}
 
-	return &config, nil
-}
+	clusterName := c.Name
+	if clusterName == "" {
+		clusterName = config.Name
+	}
+	for i := range config.Applications {
+		config.Applications[i].cluster = clusterName
+		config.Applications[i].baseDir = filepath.Dir(c.ConfigFile)
+	}
 
-func createKubernetesClient() (*kubernetes.Clientset, error) {
-	kubeconfig, err := getKubeconfigPath()
-	if err != nil {
-		err = fmt.Errorf("error getting kubeconfig path: %w", err)
-		slog.Error(err.Error())
-		return nil, err
+	if config.Charts != nil {
+		for i := range config.Charts.Releases {
+			config.Charts.Releases[i].ResolvePath(filepath.Dir(c.ConfigFile))
+		}
 	}
 
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	return &config, nil
+}
+
+// creates a clientset for the given kubeconfig context
+func createKubernetesClient(kubeContext string) (*kubernetes.Clientset, error) {
+	config, err := restConfig(kubeContext)
 	if err != nil {
 		err = fmt.Errorf("error building configs for kubeconfig: %w", err)
 		slog.Error(err.Error())
@@ -171,16 +291,13 @@ func createKubernetesClient() (*kubernetes.Clientset, error) {
 	return clientset, nil
 }
 
-func getKubeconfigPath() (string, error) {
-	var kubeconfig *string
-	if home := homedir.HomeDir(); home != "" {
-		kubeconfig = flag.String("kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
-	} else {
-		kubeconfig = flag.String("kubeconfig", "", "absolute path to the kubeconfig file")
-	}
-	flag.Parse()
+// builds the rest config for the given kubeconfig context
+func restConfig(kubeContext string) (*rest.Config, error) {
+	configLoadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	configOverrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	kubeconfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(configLoadingRules, configOverrides)
 
-	return *kubeconfig, nil
+	return kubeconfig.ClientConfig()
 }
 
 func listContexts() (map[string]*api.Context, error) {
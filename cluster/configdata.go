@@ -0,0 +1,307 @@
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	configChecksumAnnotation = "skillet.io/config-checksum"
+
+	configMapVolumePrefix = "config-"
+	secretVolumePrefix    = "secret-"
+)
+
+// an environment variable set to a value or to a config map or secret key
+type EnvVar struct {
+	Name      string `yaml:"name"`
+	Value     string `yaml:"value"`
+	ConfigMap string `yaml:"config_map"`
+	Secret    string `yaml:"secret"`
+	Key       string `yaml:"key"`
+}
+
+// a config map built from inline data and local files
+type ConfigMap struct {
+	Name      string            `yaml:"name"`
+	Data      map[string]string `yaml:"data"`
+	Files     []string          `yaml:"files"`
+	MountPath string            `yaml:"mount_path"`
+}
+
+// a secret built from local files and host environment variables
+type Secret struct {
+	Name      string   `yaml:"name"`
+	Files     []string `yaml:"files"`
+	Env       []string `yaml:"env"`
+	MountPath string   `yaml:"mount_path"`
+}
+
+// builds the config maps and secrets of an application
+func (a *Application) configResources() ([]resource, error) {
+	resources := []resource{}
+	for _, configMap := range a.ConfigMaps {
+		obj, err := a.configMap(configMap)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, obj)
+	}
+
+	for _, secret := range a.Secrets {
+		obj, err := a.secret(secret)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, obj)
+	}
+
+	return resources, nil
+}
+
+func (a *Application) configMap(configMap ConfigMap) (*apiv1.ConfigMap, error) {
+	obj := &apiv1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       configMapKind,
+			APIVersion: apiv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: a.objectMeta(configMap.Name),
+		Data:       map[string]string{},
+	}
+
+	for key, value := range configMap.Data {
+		obj.Data[key] = value
+	}
+
+	files, err := a.readFiles(configMap.Files)
+	if err != nil {
+		return nil, fmt.Errorf("error reading files of config map %s: %w", configMap.Name, err)
+	}
+
+	// config map data must be text, anything else is stored as binary data
+	for key, value := range files {
+		if utf8.Valid(value) {
+			obj.Data[key] = string(value)
+			continue
+		}
+
+		if obj.BinaryData == nil {
+			obj.BinaryData = map[string][]byte{}
+		}
+		obj.BinaryData[key] = value
+	}
+
+	return obj, nil
+}
+
+func (a *Application) secret(secret Secret) (*apiv1.Secret, error) {
+	data, err := a.readFiles(secret.Files)
+	if err != nil {
+		return nil, fmt.Errorf("error reading files of secret %s: %w", secret.Name, err)
+	}
+
+	for _, name := range secret.Env {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s for secret %s is not set", name, secret.Name)
+		}
+		data[name] = []byte(value)
+	}
+
+	return &apiv1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       secretKind,
+			APIVersion: apiv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: a.objectMeta(secret.Name),
+		Type:       apiv1.SecretTypeOpaque,
+		Data:       data,
+	}, nil
+}
+
+// reads local files into a map keyed by file name
+func (a *Application) readFiles(paths []string) (map[string][]byte, error) {
+	data := map[string][]byte{}
+	for _, path := range paths {
+		value, err := os.ReadFile(a.path(path))
+		if err != nil {
+			return nil, err
+		}
+		data[filepath.Base(path)] = value
+	}
+
+	return data, nil
+}
+
+// resolves a path in the cluster config relative to the config file
+func (a *Application) path(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(a.baseDir, path)
+}
+
+func (a *Application) env() []apiv1.EnvVar {
+	env := []apiv1.EnvVar{}
+	for _, variable := range a.Env {
+		envVar := apiv1.EnvVar{
+			Name:  variable.Name,
+			Value: variable.Value,
+		}
+
+		if variable.ConfigMap != "" {
+			envVar.ValueFrom = &apiv1.EnvVarSource{
+				ConfigMapKeyRef: &apiv1.ConfigMapKeySelector{
+					LocalObjectReference: apiv1.LocalObjectReference{Name: variable.ConfigMap},
+					Key:                  variable.Key,
+				},
+			}
+		} else if variable.Secret != "" {
+			envVar.ValueFrom = &apiv1.EnvVarSource{
+				SecretKeyRef: &apiv1.SecretKeySelector{
+					LocalObjectReference: apiv1.LocalObjectReference{Name: variable.Secret},
+					Key:                  variable.Key,
+				},
+			}
+		}
+
+		env = append(env, envVar)
+	}
+
+	return env
+}
+
+// config maps and secrets without a mount path are injected as environment variables
+func (a *Application) envFrom() []apiv1.EnvFromSource {
+	envFrom := []apiv1.EnvFromSource{}
+	for _, configMap := range a.ConfigMaps {
+		if configMap.MountPath == "" {
+			envFrom = append(envFrom, apiv1.EnvFromSource{
+				ConfigMapRef: &apiv1.ConfigMapEnvSource{
+					LocalObjectReference: apiv1.LocalObjectReference{Name: configMap.Name},
+				},
+			})
+		}
+	}
+
+	for _, secret := range a.Secrets {
+		if secret.MountPath == "" {
+			envFrom = append(envFrom, apiv1.EnvFromSource{
+				SecretRef: &apiv1.SecretEnvSource{
+					LocalObjectReference: apiv1.LocalObjectReference{Name: secret.Name},
+				},
+			})
+		}
+	}
+
+	return envFrom
+}
+
+// volumes and mounts for the config maps and secrets with a mount path
+func (a *Application) configVolumes() ([]apiv1.Volume, []apiv1.VolumeMount) {
+	volumes := []apiv1.Volume{}
+	mounts := []apiv1.VolumeMount{}
+	for _, configMap := range a.ConfigMaps {
+		if configMap.MountPath == "" {
+			continue
+		}
+
+		name := configMapVolumePrefix + configMap.Name
+		volumes = append(volumes, apiv1.Volume{
+			Name: name,
+			VolumeSource: apiv1.VolumeSource{
+				ConfigMap: &apiv1.ConfigMapVolumeSource{
+					LocalObjectReference: apiv1.LocalObjectReference{Name: configMap.Name},
+				},
+			},
+		})
+		mounts = append(mounts, apiv1.VolumeMount{
+			Name:      name,
+			MountPath: configMap.MountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	for _, secret := range a.Secrets {
+		if secret.MountPath == "" {
+			continue
+		}
+
+		name := secretVolumePrefix + secret.Name
+		volumes = append(volumes, apiv1.Volume{
+			Name: name,
+			VolumeSource: apiv1.VolumeSource{
+				Secret: &apiv1.SecretVolumeSource{
+					SecretName: secret.Name,
+				},
+			},
+		})
+		mounts = append(mounts, apiv1.VolumeMount{
+			Name:      name,
+			MountPath: secret.MountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	return volumes, mounts
+}
+
+// checksum of config data, so pods roll when it changes
+func configChecksum(resources []resource) string {
+	hash := sha256.New()
+	for _, obj := range resources {
+		data := map[string][]byte{}
+		switch typed := obj.(type) {
+		case *apiv1.ConfigMap:
+			for key, value := range typed.Data {
+				data[key] = []byte(value)
+			}
+			for key, value := range typed.BinaryData {
+				data[key] = value
+			}
+		case *apiv1.Secret:
+			data = typed.Data
+		}
+
+		keys := []string{}
+		for key := range data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(hash, "%s/%s\n", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName())
+		for _, key := range keys {
+			fmt.Fprintf(hash, "%s=%x\n", key, data[key])
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// returns the pod template of a workload
+func podTemplateOf(workload resource) *apiv1.PodTemplateSpec {
+	switch typed := workload.(type) {
+	case *appsv1.Deployment:
+		return &typed.Spec.Template
+	case *appsv1.DaemonSet:
+		return &typed.Spec.Template
+	case *appsv1.StatefulSet:
+		return &typed.Spec.Template
+	case *batchv1.Job:
+		return &typed.Spec.Template
+	case *batchv1.CronJob:
+		return &typed.Spec.JobTemplate.Spec.Template
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,149 @@
+package cluster
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var (
+	// the probe settings the api server fills in when they are not set
+	defaultProbePeriodSeconds    int32 = 10
+	defaultProbeTimeoutSeconds   int32 = 1
+	defaultProbeSuccessThreshold int32 = 1
+	defaultProbeFailureThreshold int32 = 3
+)
+
+// compute resources requested by and limited for an application's container
+type Resources struct {
+	Requests ResourceList `yaml:"requests"`
+	Limits   ResourceList `yaml:"limits"`
+}
+
+// quantities such as 250m or 512Mi
+type ResourceList struct {
+	CPU              string `yaml:"cpu"`
+	Memory           string `yaml:"memory"`
+	EphemeralStorage string `yaml:"ephemeral_storage"`
+}
+
+// a health check of an application's container
+type Probe struct {
+	HTTP *HTTPProbe `yaml:"http"`
+	TCP  *TCPProbe  `yaml:"tcp"`
+	Exec []string   `yaml:"exec"`
+
+	InitialDelaySeconds int `yaml:"initial_delay_seconds"`
+	PeriodSeconds       int `yaml:"period_seconds"`
+	TimeoutSeconds      int `yaml:"timeout_seconds"`
+	FailureThreshold    int `yaml:"failure_threshold"`
+}
+
+type HTTPProbe struct {
+	Path string `yaml:"path"`
+	Port int    `yaml:"port"`
+}
+
+type TCPProbe struct {
+	Port int `yaml:"port"`
+}
+
+// the container's resource requirements, or empty ones if none are configured
+func (r *Resources) requirements() (apiv1.ResourceRequirements, error) {
+	if r == nil {
+		return apiv1.ResourceRequirements{}, nil
+	}
+
+	requests, err := r.Requests.list()
+	if err != nil {
+		return apiv1.ResourceRequirements{}, fmt.Errorf("invalid resource requests: %w", err)
+	}
+
+	limits, err := r.Limits.list()
+	if err != nil {
+		return apiv1.ResourceRequirements{}, fmt.Errorf("invalid resource limits: %w", err)
+	}
+
+	return apiv1.ResourceRequirements{
+		Requests: requests,
+		Limits:   limits,
+	}, nil
+}
+
+// parses the set quantities, returning nil if none are set
+func (l ResourceList) list() (apiv1.ResourceList, error) {
+	amounts := map[apiv1.ResourceName]string{
+		apiv1.ResourceCPU:              l.CPU,
+		apiv1.ResourceMemory:           l.Memory,
+		apiv1.ResourceEphemeralStorage: l.EphemeralStorage,
+	}
+
+	var list apiv1.ResourceList
+	for name, amount := range amounts {
+		if amount == "" {
+			continue
+		}
+
+		quantity, err := k8sresource.ParseQuantity(amount)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s is not a valid quantity: %w", name, amount, err)
+		}
+
+		if list == nil {
+			list = apiv1.ResourceList{}
+		}
+		list[name] = quantity
+	}
+
+	return list, nil
+}
+
+// builds the kubernetes probe, or nil if it is not set
+func (a *Application) probe(probe *Probe) *apiv1.Probe {
+	if probe == nil {
+		return nil
+	}
+
+	handler := apiv1.ProbeHandler{}
+	switch {
+	case probe.HTTP != nil:
+		handler.HTTPGet = &apiv1.HTTPGetAction{
+			Path: probe.HTTP.Path,
+			Port: intstr.FromInt32(int32(a.probePort(probe.HTTP.Port))),
+		}
+	case probe.TCP != nil:
+		handler.TCPSocket = &apiv1.TCPSocketAction{
+			Port: intstr.FromInt32(int32(a.probePort(probe.TCP.Port))),
+		}
+	case len(probe.Exec) > 0:
+		handler.Exec = &apiv1.ExecAction{
+			Command: probe.Exec,
+		}
+	}
+
+	// match the api server defaults so the live probe compares equal
+	return &apiv1.Probe{
+		ProbeHandler:        handler,
+		InitialDelaySeconds: int32(probe.InitialDelaySeconds),
+		PeriodSeconds:       orDefault(probe.PeriodSeconds, defaultProbePeriodSeconds),
+		TimeoutSeconds:      orDefault(probe.TimeoutSeconds, defaultProbeTimeoutSeconds),
+		SuccessThreshold:    defaultProbeSuccessThreshold,
+		FailureThreshold:    orDefault(probe.FailureThreshold, defaultProbeFailureThreshold),
+	}
+}
+
+func orDefault(value int, fallback int32) int32 {
+	if value == 0 {
+		return fallback
+	}
+	return int32(value)
+}
+
+func (a *Application) probePort(port int) int {
+	if port != 0 || len(a.Ports) == 0 {
+		return port
+	}
+	return a.Ports[0].ContainerPort
+}
@@ -2,9 +2,11 @@ package cluster
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-	"skillet/skillet-kind/charts"
+	"os"
+	"strings"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -12,6 +14,9 @@ import (
 )
 
 func (c *Cluster) Create(ctx context.Context) error {
+	if c.Resume {
+		return c.resume(ctx)
+	}
 
 	slog.Info("Checking if cluster exists")
 	clusterExists, err := c.clusterExists()
@@ -21,63 +26,132 @@ func (c *Cluster) Create(ctx context.Context) error {
 		return err
 	} else if clusterExists {
 		err = status.Errorf(codes.AlreadyExists, "a cluster with name %v already exists", c.Name)
+		if _, loadErr := c.loadCheckpoint(); loadErr == nil {
+			err = status.Errorf(codes.AlreadyExists, "a cluster with name %v already exists. Its creation failed and can be continued with --resume", c.Name)
+		}
 		slog.Error(err.Error())
 		return err
 	}
 
-	slog.Info("Starting creation of cluster. Please note this may take some time")
+	plan, err := c.plan(ctx, false)
+	if err != nil {
+		err = fmt.Errorf("error planning cluster creation: %w", err)
+		slog.Error(err.Error())
+		return err
+	}
 
-	if c.ConfigFile == "" {
-		err = c.createWithName()
-		if err != nil {
-			err = fmt.Errorf("error creation cluster with name: %w", err)
-			slog.Error(err.Error())
-			return err
-		}
-	} else {
-		// validate cluster configuration
-		if err = c.Validate(ctx); err != nil {
-			err = fmt.Errorf("an error occurred while validating cluster configuration: %w", err)
-			slog.Error(err.Error())
-			return err
-		}
+	if c.DryRun {
+		return plan.Write(os.Stdout, c.Output)
+	}
 
-		err = c.createWithConfig()
-		if err != nil {
-			err = fmt.Errorf("error creating cluster with config: %w", err)
-			slog.Error(err.Error())
-			return err
-		}
+	cp, err := c.newCheckpoint()
+	if err != nil {
+		err = fmt.Errorf("error saving checkpoint: %w", err)
+		slog.Error(err.Error())
+		return err
+	}
 
-		slog.Info("Deploying user applications to cluster")
-		clientset, err := createKubernetesClient()
-		if err != nil {
-			err = fmt.Errorf("error setting up clientset: %w", err)
-			slog.Error(err.Error())
-			return err
-		}
+	return c.executeCreate(ctx, plan, cp)
+}
 
-		err = c.DeployApplications(ctx, clientset)
-		if err != nil {
-			err = fmt.Errorf("error deploying applications: %w", err)
-			slog.Error(err.Error())
-			return err
-		}
+// continues a failed creation from the phase that failed
+func (c *Cluster) resume(ctx context.Context) error {
+	if _, err := c.resolveName(); err != nil {
+		return err
+	}
+
+	cp, err := c.loadCheckpoint()
+	if err != nil {
+		err = fmt.Errorf("error loading checkpoint: %w", err)
+		slog.Error(err.Error())
+		return err
+	}
+
+	clusterExists, err := c.clusterExists()
+	if err != nil {
+		err = fmt.Errorf("an error occurred while checking if cluster exists: %w", err)
+		slog.Error(err.Error())
+		return err
+	} else if !clusterExists && cp.done(provisionPhase) {
+		err = status.Errorf(codes.FailedPrecondition, "cluster %s no longer exists. Create it again without --resume", c.Name)
+		slog.Error(err.Error())
+		return err
 	}
 
-	slog.Info("Applying default resources to cluster")
-	kubeContext := kindPrefix + c.Name
-	err = charts.ApplyDefaultResources(ctx, kubeContext)
+	slog.Info("Resuming cluster creation", "cluster", c.Name, "completed", strings.Join(cp.Completed, ", "))
+	plan, err := c.plan(ctx, clusterExists)
 	if err != nil {
-		err = fmt.Errorf("error while applying default resources: %w", err)
+		err = fmt.Errorf("error planning cluster creation: %w", err)
 		slog.Error(err.Error())
 		return err
 	}
 
+	err = cp.verify(plan)
+	if err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+
+	if c.DryRun {
+		return plan.Write(os.Stdout, c.Output)
+	}
+
+	// the cluster existed before resuming, so a failure never deletes it
+	c.KeepOnFailure = true
+	return c.executeCreate(ctx, plan, cp)
+}
+
+// executes the plan of a new cluster, rolling it back if it fails
+func (c *Cluster) executeCreate(ctx context.Context, plan *Plan, cp *checkpoint) error {
+	err := c.execute(ctx, plan, cp)
+	if err != nil {
+		c.rollback(err, cp)
+		err = fmt.Errorf("error creating cluster: %w", err)
+		slog.Error(err.Error())
+		return err
+	}
+
+	c.removeCheckpoint(cp)
 	slog.Info("Cluster has been successfully created")
 	return nil
 }
 
+// summarizes a failed creation and deletes the half-created cluster unless it is kept
+func (c *Cluster) rollback(err error, cp *checkpoint) {
+	var phaseErr *PhaseError
+	if !errors.As(err, &phaseErr) {
+		return
+	}
+
+	slog.Error("Cluster creation failed", "cluster", c.Name, "phase", phaseErr.Phase, "completed", strings.Join(phaseErr.Completed, ", "))
+
+	// kind removes the nodes of a cluster it fails to provision by itself, and a build failing
+	// beforehand leaves no cluster at all. The checkpoint also records provisioning done by an
+	// earlier run, which a resumed run skips
+	if !cp.done(provisionPhase) {
+		c.removeCheckpoint(cp)
+		return
+	}
+
+	if c.KeepOnFailure {
+		slog.Info("Keeping the failed cluster for debugging. Continue its creation with --resume, or delete it with skillet delete", "cluster", c.Name)
+		return
+	}
+
+	slog.Info("Deleting the failed cluster", "cluster", c.Name)
+	if err := c.deleteKindCluster(); err != nil {
+		slog.Error("Could not delete the failed cluster", "cluster", c.Name, "error", err)
+		return
+	}
+	c.removeCheckpoint(cp)
+}
+
+func (c *Cluster) removeCheckpoint(cp *checkpoint) {
+	if err := cp.remove(); err != nil {
+		slog.Warn("Could not remove checkpoint", "cluster", c.Name, "error", err)
+	}
+}
+
 // create a cluster given a cluster name
 func (c *Cluster) createWithName() error {
 	provider := kind_cluster.NewProvider()
@@ -91,18 +165,11 @@ func (c *Cluster) createWithName() error {
 	return nil
 }
 
-// create a cluster given a config file
-func (c *Cluster) createWithConfig() error {
-	kindConfig, err := c.generateKindConfig()
-	if err != nil {
-		err = fmt.Errorf("an error occurred while parsing kind config: %w", err)
-		slog.Error(err.Error())
-		return err
-	}
-
+// create a cluster given a generated kind config
+func (c *Cluster) createWithConfig(kindConfig string) error {
 	options := kind_cluster.CreateWithRawConfig([]byte(kindConfig))
 	provider := kind_cluster.NewProvider()
-	err = provider.Create(c.Name, options)
+	err := provider.Create(c.Name, options)
 	if err != nil {
 		err = fmt.Errorf("failed to create cluster from config: %w", err)
 		slog.Error(err.Error())
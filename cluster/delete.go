@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -26,21 +27,91 @@ func (c *Cluster) Delete(ctx context.Context) error {
 	// delete cluster by name
 	slog.Info("Deleting cluster", "cluster", c.Name)
 
-	kubeconfig, err := getKubeconfigPath()
+	err = c.deleteKindCluster()
 	if err != nil {
-		err = fmt.Errorf("error getting kubeconfig path: %w", err)
+		err = fmt.Errorf("error deleting cluster: %w", err)
 		slog.Error(err.Error())
 		return err
 	}
 
-	provider := kind_cluster.NewProvider()
-	err = provider.Delete(c.Name, kubeconfig)
+	// a deleted cluster has no creation left to resume
+	if path, err := c.checkpointPath(); err == nil {
+		c.removeCheckpoint(&checkpoint{path: path})
+	}
+
+	slog.Info("Cluster has been successfully deleted")
+
+	if c.RemoveRegistry {
+		return c.deleteRegistry(ctx)
+	}
+	return nil
+}
+
+// removes the cluster's local registry container, named in its config file if one is given
+func (c *Cluster) deleteRegistry(ctx context.Context) error {
+	registry := &RegistryConfig{}
+	if c.ConfigFile != "" {
+		clusterConfig, err := c.parseConfig()
+		if err != nil {
+			err = fmt.Errorf("error parsing cluster config: %w", err)
+			slog.Error(err.Error())
+			return err
+		}
+		registry = clusterConfig.Registry
+	}
+
+	// every kind cluster shares one docker network, so the registry may still serve other clusters
+	attached, err := c.registryClusters(registry)
 	if err != nil {
-		err = fmt.Errorf("error deleting cluster: %w", err)
+		err = fmt.Errorf("error finding clusters using registry %s: %w", registry.name(), err)
+		slog.Error(err.Error())
+		return err
+	}
+
+	if len(attached) > 0 && !c.ForceRemoveRegistry {
+		slog.Warn("Keeping local registry, which other clusters still pull from. Pass --force-remove-registry to remove it anyway", "registry", registry.name(), "clusters", strings.Join(attached, ", "))
+		return nil
+	}
+
+	slog.Info("Removing local registry", "registry", registry.name())
+	err = removeRegistry(ctx, registry.name())
+	if err != nil {
+		err = fmt.Errorf("error removing registry %s: %w", registry.name(), err)
 		slog.Error(err.Error())
 		return err
 	}
 
-	slog.Info("Cluster has been successfully deleted")
 	return nil
 }
+
+// returns the other kind clusters whose nodes pull through the registry
+func (c *Cluster) registryClusters(registry *RegistryConfig) ([]string, error) {
+	provider := kind_cluster.NewProvider()
+	clusters, err := provider.List()
+	if err != nil {
+		return nil, err
+	}
+
+	attached := []string{}
+	for _, name := range clusters {
+		if name == c.Name {
+			continue
+		}
+
+		mirrored, _, err := registry.mirroredNodes(name)
+		if err != nil {
+			return nil, err
+		}
+		if mirrored > 0 {
+			attached = append(attached, name)
+		}
+	}
+
+	return attached, nil
+}
+
+// deletes the kind cluster and removes it from the default kubeconfig
+func (c *Cluster) deleteKindCluster() error {
+	provider := kind_cluster.NewProvider()
+	return provider.Delete(c.Name, "")
+}
@@ -0,0 +1,233 @@
+package cluster
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	devPollInterval = 500 * time.Millisecond
+
+	// how long files must stay unchanged before a change is acted on
+	devDebounce = time.Second
+
+	devConfigKey = "cluster config"
+)
+
+// watches the cluster config and build contexts, redeploying whatever changed
+func (c *Cluster) Dev(ctx context.Context) error {
+	slog.Info("Checking if cluster exists")
+	clusterExists, err := c.clusterExists()
+	if err != nil {
+		err = fmt.Errorf("an error occurred while checking if cluster exists: %w", err)
+		slog.Error(err.Error())
+		return err
+	}
+
+	if !clusterExists {
+		err = c.Create(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// status lines take the place of the log while watching
+	logger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError + 1})))
+	defer slog.SetDefault(logger)
+
+	fmt.Fprintf(os.Stdout, "Watching %s for changes to cluster %s. Press Ctrl-C to stop\n", c.ConfigFile, c.Name)
+	watched := c.devFingerprints()
+	if clusterExists {
+		c.devCycle(ctx, os.Stdout)
+		watched = c.devFingerprints()
+	}
+
+	ticker := time.NewTicker(devPollInterval)
+	defer ticker.Stop()
+
+	var changedAt time.Time
+	changed := []string{}
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(os.Stdout, "Stopped watching. Cluster %s is still running\n", c.Name)
+			return nil
+		case <-ticker.C:
+		}
+
+		current := c.devFingerprints()
+		if diff := changedKeys(watched, current); len(diff) > 0 {
+			watched = current
+			changedAt = time.Now()
+			for _, key := range diff {
+				if !slices.Contains(changed, key) {
+					changed = append(changed, key)
+				}
+			}
+			continue
+		}
+
+		if len(changed) == 0 || time.Since(changedAt) < devDebounce {
+			continue
+		}
+
+		slices.Sort(changed)
+		fmt.Fprintf(os.Stdout, "\nChanged: %s\n", strings.Join(changed, ", "))
+		changed = []string{}
+
+		c.devCycle(ctx, os.Stdout)
+
+		// pick up edits made during the cycle
+		current = c.devFingerprints()
+		if diff := changedKeys(watched, current); len(diff) > 0 {
+			changed = diff
+			changedAt = time.Now()
+		}
+		watched = current
+	}
+}
+
+// reconciles the cluster against its config once and writes a status line per application
+func (c *Cluster) devCycle(ctx context.Context, w io.Writer) {
+	started := time.Now()
+	plan, err := c.plan(ctx, true)
+	if err == nil {
+		err = c.execute(ctx, plan, nil)
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err != nil {
+		var phaseErr *PhaseError
+		if errors.As(err, &phaseErr) {
+			fmt.Fprintf(w, "%s  failed in phase %s: %v\n", time.Now().Format(time.TimeOnly), phaseErr.Phase, phaseErr.Err)
+		} else {
+			fmt.Fprintf(w, "%s  failed: %v\n", time.Now().Format(time.TimeOnly), err)
+		}
+		if plan == nil {
+			return
+		}
+	}
+
+	config, parseErr := c.parseConfig()
+	if parseErr != nil {
+		return
+	}
+
+	width := 0
+	for _, app := range config.Applications {
+		width = max(width, len(app.Name))
+	}
+
+	for _, app := range config.Applications {
+		fmt.Fprintf(w, "%s  %-*s  %s\n", time.Now().Format(time.TimeOnly), width, app.Name, plan.appStatus(app, err))
+	}
+	fmt.Fprintf(w, "Done in %s\n", time.Since(started).Round(100*time.Millisecond))
+}
+
+// summarizes what a cycle did for an application
+func (p *Plan) appStatus(app Application, err error) string {
+	parts := []string{}
+	for _, build := range p.Builds {
+		if build.Application == app.Name && build.Action == createAction {
+			parts = append(parts, "built "+build.Image)
+		}
+	}
+
+	for _, change := range p.Resources {
+		if change.Name != app.Name || change.Namespace != app.Namespace || !slices.Contains(workloadKinds, change.Kind) {
+			continue
+		}
+
+		switch change.Action {
+		case createAction:
+			parts = append(parts, "created "+strings.ToLower(change.Kind))
+		case updateAction:
+			parts = append(parts, "rolled out "+strings.ToLower(change.Kind))
+		case replaceAction:
+			parts = append(parts, "replaced "+strings.ToLower(change.Kind))
+		}
+	}
+
+	switch {
+	case err != nil && len(parts) > 0:
+		return strings.Join(parts, ", ") + " (incomplete)"
+	case err != nil:
+		return "not updated"
+	case len(parts) == 0:
+		return "up to date"
+	default:
+		return strings.Join(parts, ", ")
+	}
+}
+
+// fingerprints the cluster config and every build context
+func (c *Cluster) devFingerprints() map[string]string {
+	fingerprints := map[string]string{}
+	fingerprints[devConfigKey], _ = c.configFileHash()
+
+	config, err := c.parseConfig()
+	if err != nil {
+		return fingerprints
+	}
+
+	for _, app := range config.Applications {
+		if app.Build == nil {
+			continue
+		}
+
+		dir := app.path(app.Build.Context)
+		fingerprints[app.Name], _ = contextFingerprint(dir, app.Build.dockerfile())
+	}
+
+	return fingerprints
+}
+
+// hashes the names, sizes, modes and modification times of a build context's files
+func contextFingerprint(dir string, dockerfile string) (string, error) {
+	files, err := contextFiles(dir, dockerfile)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	for _, file := range files {
+		info, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s %d %s %d\n", file, info.Size(), info.Mode(), info.ModTime().UnixNano())
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func changedKeys(before map[string]string, after map[string]string) []string {
+	changed := []string{}
+	for key, value := range after {
+		if previous, ok := before[key]; !ok || previous != value {
+			changed = append(changed, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+
+	slices.Sort(changed)
+	return changed
+}
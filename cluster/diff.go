@@ -0,0 +1,166 @@
+package cluster
+
+import (
+	"bytes"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// checks if the fields skillet sets differ on the live object
+func resourceChanged(desired resource, live resource) bool {
+	// typed clients do not populate the type meta of returned objects
+	live.GetObjectKind().SetGroupVersionKind(desired.GetObjectKind().GroupVersionKind())
+	return !equality.Semantic.DeepDerivative(desired, live)
+}
+
+// renders a line diff of the fields skillet sets on the desired object
+func diffResources(live resource, desired resource) (string, error) {
+	desiredFields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(redact(desired))
+	if err != nil {
+		return "", err
+	}
+	desiredFields = dropEmpty(desiredFields).(map[string]interface{})
+
+	to, err := marshalYAML(desiredFields)
+	if err != nil {
+		return "", err
+	}
+
+	from := []byte{}
+	if live != nil {
+		liveFields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(redact(live))
+		if err != nil {
+			return "", err
+		}
+
+		from, err = marshalYAML(project(liveFields, desiredFields))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return lineDiff(string(from), string(to)), nil
+}
+
+func marshalYAML(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err := encoder.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// removes nil values and empty maps left behind by unset fields
+func dropEmpty(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for key, field := range v {
+			field = dropEmpty(field)
+			if field == nil {
+				continue
+			} else if m, ok := field.(map[string]interface{}); ok && len(m) == 0 {
+				continue
+			}
+			out[key] = field
+		}
+		return out
+	case []interface{}:
+		out := []interface{}{}
+		for _, item := range v {
+			out = append(out, dropEmpty(item))
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// keeps only the parts of the live value that are also set on the desired value
+func project(live interface{}, desired interface{}) interface{} {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return live
+		}
+
+		out := map[string]interface{}{}
+		for key, field := range d {
+			if liveField, ok := l[key]; ok {
+				out[key] = project(liveField, field)
+			}
+		}
+		return out
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok {
+			return live
+		}
+
+		out := []interface{}{}
+		for i, item := range l {
+			if i < len(d) {
+				item = project(item, d[i])
+			}
+			out = append(out, item)
+		}
+		return out
+	default:
+		return live
+	}
+}
+
+// renders a line by line diff, prefixing removed lines with "-" and added lines with "+"
+func lineDiff(from string, to string) string {
+	a := splitLines(from)
+	b := splitLines(to)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			out.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			out.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+
+	return out.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,200 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apiv1 "k8s.io/api/core/v1"
+	kind_cluster "sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+)
+
+// loads images from the host's docker daemon into every node of a cluster
+func (c *Cluster) LoadImages(ctx context.Context, images []string) error {
+	if len(images) == 0 {
+		err := status.Errorf(codes.InvalidArgument, "at least one image must be given")
+		slog.Error(err.Error())
+		return err
+	}
+
+	slog.Info("Checking if cluster exists")
+	clusterExists, err := c.clusterExists()
+	if err != nil {
+		err = fmt.Errorf("an error occurred while checking if cluster exists: %w", err)
+		slog.Error(err.Error())
+		return err
+	} else if !clusterExists {
+		err = status.Errorf(codes.NotFound, "could not find cluster with name %v", c.Name)
+		slog.Error(err.Error())
+		return err
+	}
+
+	for _, image := range images {
+		if err := imageExists(ctx, image); err != nil {
+			return err
+		}
+	}
+
+	err = c.loadImages(ctx, images)
+	if err != nil {
+		err = fmt.Errorf("error loading images: %w", err)
+		slog.Error(err.Error())
+		return err
+	}
+
+	slog.Info("Images have been successfully loaded")
+	return nil
+}
+
+// returns the application images that only exist in the host's docker daemon
+func (c *Cluster) planImages(ctx context.Context, config *ClusterConfig) ([]string, error) {
+	images := []string{}
+	if len(config.Applications) == 0 {
+		return images, nil
+	}
+
+	cli, err := newDockerClient()
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	planned := map[string]bool{}
+	for i := range config.Applications {
+		app := &config.Applications[i]
+		if app.Build == nil {
+			if config.Registry.hosts(app.Image) {
+				continue
+			}
+
+			hostOnly, err := hostOnlyImage(ctx, cli, app.Image)
+			if err != nil {
+				return nil, err
+			} else if !hostOnly {
+				continue
+			}
+		}
+
+		app.loadImage = true
+		if !planned[app.Image] {
+			planned[app.Image] = true
+			images = append(images, app.Image)
+		}
+	}
+
+	return images, nil
+}
+
+// checks if an image exists on the host but could not be pulled by the nodes
+func hostOnlyImage(ctx context.Context, cli *client.Client, image string) (bool, error) {
+	info, _, err := cli.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		return false, fmt.Errorf("error finding image %s: %w", image, err)
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return true, nil
+	}
+
+	for _, repoDigest := range info.RepoDigests {
+		pulled, err := reference.ParseNormalizedNamed(repoDigest)
+		if err == nil && pulled.Name() == named.Name() {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// loads images into the nodes missing them, like `kind load docker-image`
+func (c *Cluster) loadImages(ctx context.Context, images []string) error {
+	provider := kind_cluster.NewProvider()
+	nodeList, err := provider.ListInternalNodes(c.Name)
+	if err != nil {
+		return fmt.Errorf("error listing nodes of cluster %s: %w", c.Name, err)
+	}
+
+	cli, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	for _, image := range images {
+		info, _, err := cli.ImageInspectWithRaw(ctx, image)
+		if err != nil {
+			return fmt.Errorf("error finding image %s: %w", image, err)
+		}
+
+		missing := []nodes.Node{}
+		for _, node := range nodeList {
+			id, err := nodeutils.ImageID(node, image)
+			if err != nil || id != info.ID {
+				missing = append(missing, node)
+			}
+		}
+
+		if len(missing) == 0 {
+			slog.Info("Image is already present on every node", "image", image)
+			continue
+		}
+
+		slog.Info("Loading image into nodes", "image", image, "nodes", len(missing))
+		err = loadImage(ctx, cli, image, missing)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saves an image from the host's docker daemon once, then imports it into each node
+func loadImage(ctx context.Context, cli *client.Client, image string, nodeList []nodes.Node) error {
+	archive, err := os.CreateTemp("", "skillet-image-*.tar")
+	if err != nil {
+		return fmt.Errorf("error creating archive for image %s: %w", image, err)
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	reader, err := cli.ImageSave(ctx, []string{image})
+	if err != nil {
+		return fmt.Errorf("error saving image %s: %w", image, err)
+	}
+	_, err = io.Copy(archive, reader)
+	reader.Close()
+	if err != nil {
+		return fmt.Errorf("error saving image %s: %w", image, err)
+	}
+
+	for _, node := range nodeList {
+		if _, err := archive.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		err = nodeutils.LoadImageArchive(node, archive)
+		if err != nil {
+			return fmt.Errorf("error loading image %s into node %s: %w", image, node.String(), err)
+		}
+	}
+
+	return nil
+}
+
+// loaded images cannot be pulled, even for the latest tag
+func (a *Application) imagePullPolicy() apiv1.PullPolicy {
+	if a.loadImage {
+		return apiv1.PullIfNotPresent
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+package cluster
+
+import (
+	"skillet/skillet-kind/charts"
+
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	defaultHTTPPort  = 80
+	defaultHTTPSPort = 443
+	defaultPathType  = string(networkingv1.PathTypePrefix)
+)
+
+// cluster-wide ingress controller settings
+type IngressConfig struct {
+	Enabled   bool `yaml:"enabled"`
+	HTTPPort  int  `yaml:"http_port"`
+	HTTPSPort int  `yaml:"https_port"`
+}
+
+// routes from an ingress host to an application
+type ApplicationIngress struct {
+	Host  string        `yaml:"host"`
+	Paths []IngressPath `yaml:"paths"`
+}
+
+type IngressPath struct {
+	Path     string `yaml:"path"`
+	PathType string `yaml:"path_type"`
+	Port     int    `yaml:"port"`
+}
+
+// builds an application's ingress, or nil if it has none
+func (a *Application) ingress() *networkingv1.Ingress {
+	if a.Ingress == nil {
+		return nil
+	}
+
+	paths := []networkingv1.HTTPIngressPath{}
+	for _, path := range a.Ingress.paths() {
+		pathType := networkingv1.PathType(path.PathType)
+		if pathType == "" {
+			pathType = networkingv1.PathType(defaultPathType)
+		}
+
+		paths = append(paths, networkingv1.HTTPIngressPath{
+			Path:     path.Path,
+			PathType: &pathType,
+			Backend: networkingv1.IngressBackend{
+				Service: &networkingv1.IngressServiceBackend{
+					Name: a.Name,
+					Port: networkingv1.ServiceBackendPort{
+						Number: int32(a.ingressPort(path)),
+					},
+				},
+			},
+		})
+	}
+
+	return &networkingv1.Ingress{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       ingressKind,
+			APIVersion: networkingv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: a.objectMeta(a.Name),
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: &charts.IngressClassName,
+			TLS: []networkingv1.IngressTLS{
+				{
+					Hosts:      []string{a.Ingress.Host},
+					SecretName: a.Name + tlsSecretSuffix,
+				},
+			},
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: a.Ingress.Host,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: paths,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// the service port a path routes to, defaulting to the application's first port
+func (a *Application) ingressPort(path IngressPath) int {
+	if path.Port != 0 || len(a.Ports) == 0 {
+		return path.Port
+	}
+	return a.Ports[0].ContainerPort
+}
+
+// the paths of an ingress, defaulting to routing everything
+func (i *ApplicationIngress) paths() []IngressPath {
+	if len(i.Paths) == 0 {
+		return []IngressPath{{Path: "/"}}
+	}
+	return i.Paths
+}
+
+func (i *IngressConfig) enabled() bool {
+	return i != nil && i.Enabled
+}
+
+func (i *IngressConfig) httpPort() int {
+	if i.HTTPPort != 0 {
+		return i.HTTPPort
+	}
+	return defaultHTTPPort
+}
+
+func (i *IngressConfig) httpsPort() int {
+	if i.HTTPSPort != 0 {
+		return i.HTTPSPort
+	}
+	return defaultHTTPSPort
+}
+
+// forwards the host's http and https ports to the ingress controller
+func (i *IngressConfig) portMappings() []PortMapping {
+	if !i.enabled() {
+		return []PortMapping{}
+	}
+
+	return []PortMapping{
+		{
+			ContainerPort: defaultHTTPPort,
+			HostPort:      i.httpPort(),
+			Protocol:      string(apiv1.ProtocolTCP),
+		},
+		{
+			ContainerPort: defaultHTTPSPort,
+			HostPort:      i.httpsPort(),
+			Protocol:      string(apiv1.ProtocolTCP),
+		},
+	}
+}
@@ -0,0 +1,151 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	jobType     = "job"
+	cronjobType = "cronjob"
+
+	jobNameLabel = "job-name"
+
+	jobPollInterval = 2 * time.Second
+	podLogLines     = int64(50)
+)
+
+// builds the job for an application
+func (a *Application) job() *batchv1.Job {
+	return &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       jobKind,
+			APIVersion: batchv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: a.objectMeta(a.Name),
+		Spec:       a.jobSpec(),
+	}
+}
+
+// builds the cronjob for an application
+func (a *Application) cronjob() *batchv1.CronJob {
+	return &batchv1.CronJob{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       cronjobKind,
+			APIVersion: batchv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: a.objectMeta(a.Name),
+		Spec: batchv1.CronJobSpec{
+			Schedule: a.Schedule,
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: a.jobSpec(),
+			},
+		},
+	}
+}
+
+func (a *Application) jobSpec() batchv1.JobSpec {
+	template := a.podTemplate()
+	template.Spec.RestartPolicy = apiv1.RestartPolicyNever
+
+	spec := batchv1.JobSpec{
+		Template: template,
+	}
+	if a.BackoffLimit != nil {
+		spec.BackoffLimit = int32Ptr(*a.BackoffLimit)
+	}
+	if a.Completions != nil {
+		spec.Completions = int32Ptr(*a.Completions)
+	}
+
+	return spec
+}
+
+// waits for a job to complete, returning the logs of its pods if it fails
+func waitForJob(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string, timeout time.Duration) error {
+	slog.Info("Waiting for job to complete", "namespace", namespace, "job", name)
+
+	var failure string
+	err := wait.PollUntilContextTimeout(ctx, jobPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		job, err := clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		for _, condition := range job.Status.Conditions {
+			if condition.Status != apiv1.ConditionTrue {
+				continue
+			}
+
+			switch condition.Type {
+			case batchv1.JobComplete:
+				return true, nil
+			case batchv1.JobFailed:
+				failure = condition.Message
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+	if wait.Interrupted(err) {
+		failure = fmt.Sprintf("did not complete within %s", timeout)
+	} else if err != nil {
+		return fmt.Errorf("error waiting for job %s: %w", name, err)
+	}
+
+	if failure != "" {
+		logs := jobLogs(ctx, clientset, namespace, name)
+		slog.Error("Job failed", "namespace", namespace, "job", name, "reason", failure, "logs", logs)
+		return fmt.Errorf("job %s failed: %s\n%s", name, failure, logs)
+	}
+
+	slog.Info("Job completed", "namespace", namespace, "job", name)
+	return nil
+}
+
+// collects the last lines of logs from every pod of a job
+func jobLogs(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) string {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: jobNameLabel + "=" + name})
+	if err != nil {
+		return fmt.Sprintf("could not list pods of job %s: %v", name, err)
+	}
+
+	var b strings.Builder
+	for _, pod := range pods.Items {
+		b.WriteString(podLogs(ctx, clientset, pod))
+	}
+
+	return b.String()
+}
+
+// returns the last lines of logs of a pod's containers, from the previous run if they restarted
+func podLogs(ctx context.Context, clientset *kubernetes.Clientset, pod apiv1.Pod) string {
+	var b strings.Builder
+	for _, container := range pod.Status.ContainerStatuses {
+		options := &apiv1.PodLogOptions{
+			Container: container.Name,
+			TailLines: &podLogLines,
+			Previous:  container.RestartCount > 0 && container.State.Running == nil,
+		}
+
+		fmt.Fprintf(&b, "--- logs of pod %s, container %s ---\n", pod.Name, container.Name)
+		raw, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, options).Do(ctx).Raw()
+		if err != nil {
+			fmt.Fprintf(&b, "could not get logs: %v\n", err)
+			continue
+		}
+		b.Write(raw)
+	}
+
+	return b.String()
+}
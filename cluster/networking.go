@@ -0,0 +1,208 @@
+package cluster
+
+import (
+	"net"
+	"skillet/skillet-kind/charts"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ipv4Family = "ipv4"
+	ipv6Family = "ipv6"
+	dualFamily = "dual"
+
+	kubeProxyModes = []string{"iptables", "ipvs", "none"}
+	noKubeProxy    = "none"
+
+	// kind's default pod subnets
+	defaultPodSubnets = map[string]string{
+		ipv4Family: "10.244.0.0/16",
+		ipv6Family: "fd00:10:244::/56",
+		dualFamily: "10.244.0.0/16,fd00:10:244::/56",
+	}
+
+	// kind's default service subnets
+	defaultServiceSubnets = map[string]string{
+		ipv4Family: "10.96.0.0/16",
+		ipv6Family: "fd00:10:96::/112",
+		dualFamily: "10.96.0.0/16,fd00:10:96::/112",
+	}
+)
+
+// cluster-wide network settings
+type NetworkingConfig struct {
+	IPFamily          string `yaml:"ip_family"`
+	APIServerPort     int    `yaml:"api_server_port"`
+	PodSubnet         string `yaml:"pod_subnet"`
+	ServiceSubnet     string `yaml:"service_subnet"`
+	KubeProxyMode     string `yaml:"kube_proxy_mode"`
+	DisableDefaultCNI bool   `yaml:"disable_default_cni"`
+
+	// the CNI plugin installed when the default one is disabled
+	CNI string `yaml:"cni"`
+}
+
+type KindNetworking struct {
+	IPFamily          string `yaml:"ipFamily,omitempty"`
+	APIServerPort     int    `yaml:"apiServerPort,omitempty"`
+	PodSubnet         string `yaml:"podSubnet,omitempty"`
+	ServiceSubnet     string `yaml:"serviceSubnet,omitempty"`
+	KubeProxyMode     string `yaml:"kubeProxyMode,omitempty"`
+	DisableDefaultCNI bool   `yaml:"disableDefaultCNI,omitempty"`
+}
+
+// the kind networking settings, or nil if kind's defaults are used
+func (n *NetworkingConfig) kindNetworking() *KindNetworking {
+	if n == nil {
+		return nil
+	}
+
+	return &KindNetworking{
+		IPFamily:          n.IPFamily,
+		APIServerPort:     n.APIServerPort,
+		PodSubnet:         n.PodSubnet,
+		ServiceSubnet:     n.ServiceSubnet,
+		KubeProxyMode:     n.KubeProxyMode,
+		DisableDefaultCNI: n.DisableDefaultCNI,
+	}
+}
+
+func (n *NetworkingConfig) ipFamily() string {
+	if n == nil || n.IPFamily == "" {
+		return ipv4Family
+	}
+	return n.IPFamily
+}
+
+func (n *NetworkingConfig) podSubnets() string {
+	if n != nil && n.PodSubnet != "" {
+		return n.PodSubnet
+	}
+	return defaultPodSubnets[n.ipFamily()]
+}
+
+func (n *NetworkingConfig) serviceSubnets() string {
+	if n != nil && n.ServiceSubnet != "" {
+		return n.ServiceSubnet
+	}
+	return defaultServiceSubnets[n.ipFamily()]
+}
+
+// the CNI chart to install, or nil if kind installs its default one
+func (n *NetworkingConfig) cniChart(clusterName string) *charts.HelmChart {
+	if n == nil || !n.DisableDefaultCNI {
+		return nil
+	}
+
+	chart := charts.CNI(n.CNI, charts.CNIOptions{
+		PodSubnets:       n.podSubnets(),
+		NoKubeProxy:      n.KubeProxyMode == noKubeProxy,
+		ControlPlaneHost: clusterName + "-" + controlPlaneRole,
+	})
+	return &chart
+}
+
+func validateNetworking(networking *NetworkingConfig) error {
+	if networking == nil {
+		return nil
+	}
+
+	family := networking.ipFamily()
+	switch family {
+	case ipv4Family, ipv6Family, dualFamily:
+	default:
+		return status.Errorf(codes.FailedPrecondition, "ip family must be one of [%s, %s, %s]", ipv4Family, ipv6Family, dualFamily)
+	}
+
+	if err := validateSubnets("pod subnet", networking.PodSubnet, family); err != nil {
+		return err
+	}
+
+	if err := validateSubnets("service subnet", networking.ServiceSubnet, family); err != nil {
+		return err
+	}
+
+	if err := validateSubnetsOverlap(networking.podSubnets(), networking.serviceSubnets()); err != nil {
+		return err
+	}
+
+	if networking.APIServerPort < 0 || networking.APIServerPort > 65535 {
+		return status.Errorf(codes.FailedPrecondition, "api server port %d must be between 1 and 65535", networking.APIServerPort)
+	}
+
+	if networking.KubeProxyMode != "" && !slices.Contains(kubeProxyModes, networking.KubeProxyMode) {
+		return status.Errorf(codes.FailedPrecondition, "kube proxy mode must be one of [%s]", strings.Join(kubeProxyModes, ", "))
+	}
+
+	switch {
+	case !networking.DisableDefaultCNI && networking.CNI != "":
+		return status.Errorf(codes.FailedPrecondition, "a cni can only be chosen when the default cni is disabled")
+	case !networking.DisableDefaultCNI:
+	case networking.CNI != charts.CalicoCNI && networking.CNI != charts.CiliumCNI:
+		return status.Errorf(codes.FailedPrecondition, "the default cni is disabled, so cni must be one of [%s, %s]", charts.CalicoCNI, charts.CiliumCNI)
+	case networking.KubeProxyMode == noKubeProxy && networking.CNI != charts.CiliumCNI:
+		return status.Errorf(codes.FailedPrecondition, "kube proxy can only be disabled with the %s cni, which replaces it", charts.CiliumCNI)
+	}
+
+	return nil
+}
+
+// checks subnets match the ip family
+func validateSubnets(name string, subnets string, family string) error {
+	if subnets == "" {
+		return nil
+	}
+
+	hasIPv4, hasIPv6 := false, false
+	parts := strings.Split(subnets, ",")
+	for _, subnet := range parts {
+		ip, _, err := net.ParseCIDR(strings.TrimSpace(subnet))
+		if err != nil {
+			return status.Errorf(codes.FailedPrecondition, "%s %s is not a valid CIDR", name, subnet)
+		}
+
+		if ip.To4() != nil {
+			hasIPv4 = true
+		} else {
+			hasIPv6 = true
+		}
+	}
+
+	switch {
+	case family == dualFamily && (len(parts) != 2 || !hasIPv4 || !hasIPv6):
+		return status.Errorf(codes.FailedPrecondition, "%s of a dual stack cluster must be an ipv4 and an ipv6 CIDR separated by a comma", name)
+	case family == ipv4Family && (len(parts) != 1 || !hasIPv4):
+		return status.Errorf(codes.FailedPrecondition, "%s of an ipv4 cluster must be a single ipv4 CIDR", name)
+	case family == ipv6Family && (len(parts) != 1 || !hasIPv6):
+		return status.Errorf(codes.FailedPrecondition, "%s of an ipv6 cluster must be a single ipv6 CIDR", name)
+	}
+
+	return nil
+}
+
+// checks pod and service subnets do not overlap
+func validateSubnetsOverlap(podSubnets string, serviceSubnets string) error {
+	for _, pod := range strings.Split(podSubnets, ",") {
+		_, podNet, err := net.ParseCIDR(strings.TrimSpace(pod))
+		if err != nil {
+			continue
+		}
+
+		for _, service := range strings.Split(serviceSubnets, ",") {
+			_, serviceNet, err := net.ParseCIDR(strings.TrimSpace(service))
+			if err != nil {
+				continue
+			}
+
+			if podNet.Contains(serviceNet.IP) || serviceNet.Contains(podNet.IP) {
+				return status.Errorf(codes.FailedPrecondition, "pod subnet %s overlaps service subnet %s", podNet, serviceNet)
+			}
+		}
+	}
+
+	return nil
+}
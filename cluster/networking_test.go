@@ -0,0 +1,59 @@
+package cluster
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidateNetworkingSubnetsOverlap(t *testing.T) {
+	tests := []struct {
+		name       string
+		networking NetworkingConfig
+		valid      bool
+	}{
+		{
+			name:       "defaults",
+			networking: NetworkingConfig{},
+			valid:      true,
+		},
+		{
+			name:       "separate subnets",
+			networking: NetworkingConfig{PodSubnet: "10.100.0.0/16", ServiceSubnet: "10.200.0.0/16"},
+			valid:      true,
+		},
+		{
+			name:       "same subnet",
+			networking: NetworkingConfig{PodSubnet: "10.100.0.0/16", ServiceSubnet: "10.100.0.0/16"},
+		},
+		{
+			name:       "service subnet inside pod subnet",
+			networking: NetworkingConfig{PodSubnet: "10.0.0.0/8", ServiceSubnet: "10.96.0.0/16"},
+		},
+		{
+			name:       "pod subnet inside default service subnet",
+			networking: NetworkingConfig{PodSubnet: "10.96.128.0/17"},
+		},
+		{
+			name:       "dual stack overlapping on ipv6",
+			networking: NetworkingConfig{IPFamily: dualFamily, PodSubnet: "10.244.0.0/16,fd00:10::/32", ServiceSubnet: "10.96.0.0/16,fd00:10:96::/112"},
+		},
+		{
+			name:       "dual stack",
+			networking: NetworkingConfig{IPFamily: dualFamily},
+			valid:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateNetworking(&test.networking)
+			if test.valid && err != nil {
+				t.Fatalf("expected the subnets to be valid, got %v", err)
+			} else if !test.valid && status.Code(err) != codes.FailedPrecondition {
+				t.Fatalf("expected a FailedPrecondition error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,380 @@
+package cluster
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/homedir"
+)
+
+var (
+	nodeImageRepository = "kindest/node"
+
+	// node images of kind v0.22.0 per kubernetes minor version
+	nodeImages = map[string]string{
+		"1.29": "kindest/node:v1.29.2@sha256:51a1434a5397193442f0be2a297b488b6c919ce8a3931be0ce822606ea5ca245",
+		"1.28": "kindest/node:v1.28.7@sha256:9bc6c451a289cf96ad0bbaf33d416901de6fd632415b076ab05f5fa7e4f65c58",
+		"1.27": "kindest/node:v1.27.11@sha256:681253009e68069b8e01aad36a1e0fa8cf18bb0ab3e5c4069b2e65cafdd70843",
+		"1.26": "kindest/node:v1.26.14@sha256:5d548739ddef37b9318c70cb977f57bf3e5015e4552be4e27e57280a8cbb8e4f",
+		"1.25": "kindest/node:v1.25.16@sha256:e8b50f8e06b44bb65a93678a65a26248fae585b3d3c2a669e5ca6c90c69dc519",
+		"1.24": "kindest/node:v1.24.17@sha256:bad10f9b98d54586cba05a7eaa1b61c6b90bfc4ee174fdc43a7b75ca75c95e51",
+		"1.23": "kindest/node:v1.23.17@sha256:14d0a9a892b943866d7e6be119a06871291c517d279aedb816a4b4bc0ec0a5b3",
+	}
+
+	// system directories that must not be mounted into nodes
+	unsafeHostPaths = []string{"/", "/bin", "/home", "/lib", "/lib64", "/opt", "/root", "/run", "/sbin", "/tmp", "/usr", "/var", "/var/lib", "/var/run", "/Users"}
+
+	// system directories nothing inside of may be mounted into nodes
+	unsafeHostTrees = []string{"/boot", "/dev", "/etc", "/proc", "/sys", "/var/lib/docker", "/var/lib/containerd"}
+
+	kubernetesVersionPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?$`)
+	nodeImageTagPattern      = regexp.MustCompile(`^` + regexp.QuoteMeta(nodeImageRepository) + `:(v[0-9][^@]*)`)
+)
+
+// a single node of the cluster
+type Node struct {
+	Role              string            `yaml:"role"`
+	Image             string            `yaml:"image"`
+	Labels            map[string]string `yaml:"labels"`
+	Taints            []Taint           `yaml:"taints"`
+	ExtraMounts       []Mount           `yaml:"extra_mounts"`
+	ExtraPortMappings []NodePortMapping `yaml:"extra_port_mappings"`
+}
+
+type Taint struct {
+	Key    string `yaml:"key"`
+	Value  string `yaml:"value"`
+	Effect string `yaml:"effect"`
+}
+
+// a host path mounted into a node
+type Mount struct {
+	HostPath      string `yaml:"host_path"`
+	ContainerPath string `yaml:"container_path"`
+	ReadOnly      bool   `yaml:"read_only"`
+}
+
+// a host port forwarded to a port of a node
+type NodePortMapping struct {
+	ContainerPort int    `yaml:"container_port"`
+	HostPort      int    `yaml:"host_port"`
+	Protocol      string `yaml:"protocol"`
+}
+
+// the listed nodes, or the ones the control plane and worker counts expand to
+func (n *NodesConfig) nodes() []Node {
+	if len(n.List) > 0 {
+		return n.List
+	}
+
+	nodes := []Node{}
+	for i := 0; i < n.ControlPlane; i++ {
+		nodes = append(nodes, Node{Role: controlPlaneRole})
+	}
+	for i := 0; i < n.Worker; i++ {
+		nodes = append(nodes, Node{Role: workerRole})
+	}
+
+	return nodes
+}
+
+// the image of a node, or kind's default image if none is set
+func (c *ClusterConfig) nodeImage(node Node) string {
+	if node.Image != "" {
+		return node.Image
+	}
+	if c.KubernetesVersion == "" {
+		return ""
+	}
+
+	image, _ := nodeImageFor(c.KubernetesVersion)
+	return image
+}
+
+// builds the kind config of a node
+func (c *ClusterConfig) kindNode(node Node, baseDir string) (KindNode, error) {
+	kindNode := KindNode{
+		Role:   node.Role,
+		Image:  c.nodeImage(node),
+		Labels: node.Labels,
+	}
+
+	for _, mount := range node.ExtraMounts {
+		kindNode.ExtraMounts = append(kindNode.ExtraMounts, KindMount{
+			HostPath:      hostPath(mount.HostPath, baseDir),
+			ContainerPath: mount.ContainerPath,
+			ReadOnly:      mount.ReadOnly,
+		})
+	}
+
+	for _, mapping := range node.ExtraPortMappings {
+		kindNode.ExtraPortMappings = append(kindNode.ExtraPortMappings, PortMapping{
+			ContainerPort: mapping.ContainerPort,
+			HostPort:      mapping.HostPort,
+			Protocol:      string(mapping.protocol()),
+		})
+	}
+
+	patches, err := taintPatches(node.Taints)
+	if err != nil {
+		return KindNode{}, err
+	}
+	kindNode.KubeadmConfigPatches = patches
+
+	return kindNode, nil
+}
+
+// kubeadm init and join patches registering a node with taints
+func taintPatches(taints []Taint) ([]string, error) {
+	if len(taints) == 0 {
+		return nil, nil
+	}
+
+	nodeTaints := []map[string]string{}
+	for _, taint := range taints {
+		nodeTaint := map[string]string{
+			"key":    taint.Key,
+			"effect": taint.Effect,
+		}
+		if taint.Value != "" {
+			nodeTaint["value"] = taint.Value
+		}
+		nodeTaints = append(nodeTaints, nodeTaint)
+	}
+
+	patches := []string{}
+	for _, kind := range []string{"InitConfiguration", "JoinConfiguration"} {
+		patch, err := yaml.Marshal(map[string]interface{}{
+			"kind": kind,
+			"nodeRegistration": map[string]interface{}{
+				"taints": nodeTaints,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling taints: %w", err)
+		}
+		patches = append(patches, string(patch))
+	}
+
+	return patches, nil
+}
+
+func (m *NodePortMapping) protocol() apiv1.Protocol {
+	if m.Protocol == "" {
+		return apiv1.ProtocolTCP
+	}
+	return apiv1.Protocol(m.Protocol)
+}
+
+func hostPath(path string, baseDir string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(baseDir, path)
+}
+
+// returns the node image for a kubernetes version such as 1.29 or v1.29.2
+func nodeImageFor(version string) (string, error) {
+	match := kubernetesVersionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return "", status.Errorf(codes.FailedPrecondition, "kubernetes version %q must look like 1.29 or v1.29.2", version)
+	}
+
+	minor := match[1] + "." + match[2]
+	image, ok := nodeImages[minor]
+	if !ok {
+		return "", status.Errorf(codes.FailedPrecondition, "kubernetes version %s is not supported, supported versions are [%s]", version, strings.Join(supportedVersions(), ", "))
+	}
+
+	// a patch version must be the one the node image was built for
+	if match[3] != "" {
+		want := fmt.Sprintf("%s:v%s.%s@", nodeImageRepository, minor, match[3])
+		if !strings.HasPrefix(image, want) {
+			return "", status.Errorf(codes.FailedPrecondition, "kubernetes version %s is not supported, the node image for %s is %s", version, minor, image)
+		}
+	}
+
+	return image, nil
+}
+
+// kubernetes minor versions with a known node image, newest first
+func supportedVersions() []string {
+	versions := []string{}
+	for version := range nodeImages {
+		versions = append(versions, version)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		var iMajor, iMinor, jMajor, jMinor int
+		fmt.Sscanf(versions[i], "%d.%d", &iMajor, &iMinor)
+		fmt.Sscanf(versions[j], "%d.%d", &jMajor, &jMinor)
+		if iMajor != jMajor {
+			return iMajor > jMajor
+		}
+		return iMinor > jMinor
+	})
+
+	return versions
+}
+
+func validateNodes(config *ClusterConfig, baseDir string) error {
+	if config.KubernetesVersion != "" {
+		if _, err := nodeImageFor(config.KubernetesVersion); err != nil {
+			return err
+		}
+	}
+
+	if len(config.Nodes.List) > 0 && (config.Nodes.ControlPlane != 0 || config.Nodes.Worker != 0) {
+		return status.Errorf(codes.FailedPrecondition, "nodes must either be counted with control_plane and worker or listed with list, not both")
+	}
+
+	// validate worker nodes is not negative
+	if config.Nodes.Worker < 0 {
+		return status.Errorf(codes.FailedPrecondition, "the number of worker nodes cannot be negative")
+	}
+
+	controlPlanes := 0
+	for i, node := range config.Nodes.nodes() {
+		switch node.Role {
+		case controlPlaneRole:
+			controlPlanes++
+		case workerRole:
+		default:
+			return status.Errorf(codes.FailedPrecondition, "node %d has role %q, which must be one of [%s, %s]", i, node.Role, controlPlaneRole, workerRole)
+		}
+
+		if err := validateNodeImage(node.Image); err != nil {
+			return fmt.Errorf("node %d has an invalid image: %w", i, err)
+		}
+
+		if err := validateNode(node, baseDir); err != nil {
+			return fmt.Errorf("node %d is invalid: %w", i, err)
+		}
+	}
+
+	// validate control plane nodes is at least 1
+	if controlPlanes < 1 {
+		return status.Errorf(codes.FailedPrecondition, "the number of control plane nodes must be greater than 0")
+	}
+
+	return nil
+}
+
+func validateNode(node Node, baseDir string) error {
+	for key, value := range node.Labels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return status.Errorf(codes.FailedPrecondition, "label key %q is invalid: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return status.Errorf(codes.FailedPrecondition, "label value %q is invalid: %s", value, strings.Join(errs, ", "))
+		}
+	}
+
+	for _, taint := range node.Taints {
+		if errs := validation.IsQualifiedName(taint.Key); len(errs) > 0 {
+			return status.Errorf(codes.FailedPrecondition, "taint key %q is invalid: %s", taint.Key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(taint.Value); len(errs) > 0 {
+			return status.Errorf(codes.FailedPrecondition, "taint value %q is invalid: %s", taint.Value, strings.Join(errs, ", "))
+		}
+
+		switch apiv1.TaintEffect(taint.Effect) {
+		case apiv1.TaintEffectNoSchedule, apiv1.TaintEffectPreferNoSchedule, apiv1.TaintEffectNoExecute:
+		default:
+			return status.Errorf(codes.FailedPrecondition, "taint effect must be one of [NoSchedule, PreferNoSchedule, NoExecute]")
+		}
+	}
+
+	for _, mount := range node.ExtraMounts {
+		if err := validateHostPath(hostPath(mount.HostPath, baseDir)); err != nil {
+			return err
+		}
+
+		if !path.IsAbs(mount.ContainerPath) {
+			return status.Errorf(codes.FailedPrecondition, "container path %q must be absolute", mount.ContainerPath)
+		}
+	}
+
+	for _, mapping := range node.ExtraPortMappings {
+		if mapping.ContainerPort < 1 || mapping.ContainerPort > 65535 {
+			return status.Errorf(codes.FailedPrecondition, "container port %d must be between 1 and 65535", mapping.ContainerPort)
+		}
+
+		if mapping.HostPort < 1 || mapping.HostPort > 65535 {
+			return status.Errorf(codes.FailedPrecondition, "host port %d must be between 1 and 65535", mapping.HostPort)
+		}
+
+		switch mapping.protocol() {
+		case apiv1.ProtocolTCP, apiv1.ProtocolUDP, apiv1.ProtocolSCTP:
+		default:
+			return status.Errorf(codes.FailedPrecondition, "port protocol must be one of [TCP, UDP, SCTP]")
+		}
+	}
+
+	return nil
+}
+
+// rejects host paths that would expose the host system to nodes
+func validateHostPath(hostPath string) error {
+	if hostPath == "" {
+		return status.Errorf(codes.FailedPrecondition, "host path must not be empty")
+	}
+
+	if filepath.Base(hostPath) == "docker.sock" || filepath.Base(hostPath) == "containerd.sock" {
+		return status.Errorf(codes.FailedPrecondition, "host path %s is a container runtime socket, which must not be mounted", hostPath)
+	}
+
+	if home := homedir.HomeDir(); home != "" && hostPath == filepath.Clean(home) {
+		return status.Errorf(codes.FailedPrecondition, "host path %s is the home directory, mount a directory inside it instead", hostPath)
+	}
+
+	for _, unsafe := range unsafeHostPaths {
+		if hostPath == unsafe {
+			return status.Errorf(codes.FailedPrecondition, "host path %s is a system directory, which must not be mounted", hostPath)
+		}
+	}
+
+	for _, unsafe := range unsafeHostTrees {
+		if hostPath == unsafe || strings.HasPrefix(hostPath, unsafe+"/") {
+			return status.Errorf(codes.FailedPrecondition, "host path %s is inside %s, which must not be mounted", hostPath, unsafe)
+		}
+	}
+
+	return nil
+}
+
+// checks kindest/node images are for a supported kubernetes version
+func validateNodeImage(image string) error {
+	if image == "" {
+		return nil
+	}
+
+	if strings.ContainsAny(image, " \t\n") {
+		return status.Errorf(codes.FailedPrecondition, "image %q must not contain whitespace", image)
+	}
+
+	match := nodeImageTagPattern.FindStringSubmatch(image)
+	if match == nil {
+		return nil
+	}
+
+	version := kubernetesVersionPattern.FindStringSubmatch(match[1])
+	if version == nil {
+		return nil
+	}
+
+	if _, ok := nodeImages[version[1]+"."+version[2]]; !ok {
+		return status.Errorf(codes.FailedPrecondition, "image %s is for kubernetes %s, which is not supported, supported versions are [%s]", image, match[1], strings.Join(supportedVersions(), ", "))
+	}
+
+	return nil
+}
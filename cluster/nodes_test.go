@@ -0,0 +1,76 @@
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidateHostPath(t *testing.T) {
+	tests := []struct {
+		path  string
+		valid bool
+	}{
+		{path: "", valid: false},
+		{path: "/", valid: false},
+		{path: "/var/lib", valid: false},
+		{path: "/etc/kubernetes", valid: false},
+		{path: "/var/lib/docker/volumes", valid: false},
+		{path: "/var/run/docker.sock", valid: false},
+		{path: "/run/containerd/containerd.sock", valid: false},
+		{path: "/etcetera", valid: true},
+		{path: "/srv/data", valid: true},
+		{path: "/var/lib/app", valid: true},
+	}
+
+	for _, test := range tests {
+		err := validateHostPath(test.path)
+		if test.valid && err != nil {
+			t.Errorf("expected host path %q to be valid, got %v", test.path, err)
+		} else if !test.valid && status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("expected host path %q to be rejected with a FailedPrecondition error, got %v", test.path, err)
+		}
+	}
+}
+
+func TestNodeImageFor(t *testing.T) {
+	tests := []struct {
+		version string
+		image   string
+		valid   bool
+	}{
+		{version: "1.29", image: "kindest/node:v1.29.2@", valid: true},
+		{version: "v1.29", image: "kindest/node:v1.29.2@", valid: true},
+		{version: "v1.29.2", image: "kindest/node:v1.29.2@", valid: true},
+		{version: "1.23.17", image: "kindest/node:v1.23.17@", valid: true},
+		{version: "1.29.1", valid: false},
+		{version: "1.22", valid: false},
+		{version: "latest", valid: false},
+		{version: "", valid: false},
+	}
+
+	for _, test := range tests {
+		image, err := nodeImageFor(test.version)
+		if !test.valid {
+			if status.Code(err) != codes.FailedPrecondition {
+				t.Errorf("expected version %q to be rejected with a FailedPrecondition error, got %v", test.version, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("expected version %q to be supported, got %v", test.version, err)
+		} else if !strings.HasPrefix(image, test.image) {
+			t.Errorf("expected version %q to use %s, got %s", test.version, test.image, image)
+		}
+	}
+}
+
+func TestSupportedVersionsNewestFirst(t *testing.T) {
+	versions := supportedVersions()
+	if len(versions) != len(nodeImages) || versions[0] != "1.29" || versions[len(versions)-1] != "1.23" {
+		t.Fatalf("expected versions from 1.29 down to 1.23, got %v", versions)
+	}
+}
@@ -0,0 +1,82 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	featureGatePattern   = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+	runtimeConfigPattern = regexp.MustCompile(`^[a-z0-9.\-]+(/[a-z0-9.\-]+)*$`)
+)
+
+// checks the patches, feature gates and runtime config passed to kind
+func validatePatches(config *ClusterConfig) error {
+	for i, patch := range config.KubeadmConfigPatches {
+		if err := validateKubeadmPatch(patch); err != nil {
+			return fmt.Errorf("kubeadm config patch %d is invalid: %w", i, err)
+		}
+	}
+
+	for i, patch := range config.ContainerdConfigPatches {
+		var decoded map[string]interface{}
+		if _, err := toml.Decode(patch, &decoded); err != nil {
+			return status.Errorf(codes.FailedPrecondition, "containerd config patch %d is not valid TOML: %v", i, err)
+		}
+	}
+
+	for name := range config.FeatureGates {
+		if !featureGatePattern.MatchString(name) {
+			return status.Errorf(codes.FailedPrecondition, "feature gate %q must be a name such as EphemeralContainers", name)
+		}
+	}
+
+	for key, value := range config.RuntimeConfig {
+		if !runtimeConfigPattern.MatchString(key) {
+			return status.Errorf(codes.FailedPrecondition, "runtime config key %q must be an api group version such as api/alpha or batch/v2alpha1", key)
+		}
+		if value != "true" && value != "false" {
+			return status.Errorf(codes.FailedPrecondition, "runtime config %s must be true or false", key)
+		}
+	}
+
+	return nil
+}
+
+// kubeadm patches are YAML documents naming the kind of kubeadm configuration they patch
+func validateKubeadmPatch(patch string) error {
+	decoder := yaml.NewDecoder(strings.NewReader(patch))
+	documents := 0
+	for {
+		var document map[string]interface{}
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return status.Errorf(codes.FailedPrecondition, "patch is not a valid YAML mapping: %v", err)
+		}
+
+		if document == nil {
+			continue
+		}
+		documents++
+
+		if kind, ok := document["kind"].(string); !ok || kind == "" {
+			return status.Errorf(codes.FailedPrecondition, "patch must set the kind of kubeadm configuration it applies to, such as ClusterConfiguration")
+		}
+	}
+
+	if documents == 0 {
+		return status.Errorf(codes.FailedPrecondition, "patch is empty")
+	}
+
+	return nil
+}
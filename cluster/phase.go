@@ -0,0 +1,64 @@
+package cluster
+
+import (
+	"fmt"
+	"log/slog"
+	"skillet/skillet-kind/charts"
+)
+
+var (
+	buildPhase        = "build"
+	provisionPhase    = "provision"
+	registryPhase     = "registry"
+	cniPhase          = "cni"
+	imagesPhase       = "images"
+	namespacesPhase   = "namespaces"
+	applicationsPhase = "applications"
+	rolloutPhase      = "rollout"
+	chartPhasePrefix  = "chart/"
+)
+
+// PhaseError is returned when a phase of creating or applying a cluster fails
+type PhaseError struct {
+	Phase     string
+	Completed []string
+	Err       error
+}
+
+func (e *PhaseError) Error() string {
+	return fmt.Sprintf("phase %s failed: %v", e.Phase, e.Err)
+}
+
+func (e *PhaseError) Unwrap() error {
+	return e.Err
+}
+
+// runs phases in order, skipping and recording the ones in the checkpoint
+func newPhaseRunner(cp *checkpoint) func(phase string, fn func() error) error {
+	completed := []string{}
+	return func(phase string, fn func() error) error {
+		if cp.done(phase) {
+			slog.Info("Skipping completed phase", "phase", phase)
+			completed = append(completed, phase)
+			return nil
+		}
+
+		slog.Info("Starting phase", "phase", phase)
+		err := fn()
+		if err == nil {
+			err = cp.complete(phase)
+		}
+		if err != nil {
+			slog.Error(err.Error())
+			return &PhaseError{Phase: phase, Completed: completed, Err: err}
+		}
+
+		completed = append(completed, phase)
+		return nil
+	}
+}
+
+// the phase installing a helm chart
+func chartPhase(chart charts.HelmChart) string {
+	return chartPhasePrefix + chart.Name
+}
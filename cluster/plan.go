@@ -0,0 +1,762 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"skillet/skillet-kind/charts"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	createAction    = "create"
+	updateAction    = "update"
+	replaceAction   = "replace"
+	deleteAction    = "delete"
+	unchangedAction = "unchanged"
+	waitAction      = "wait"
+
+	actionSymbols = map[string]string{
+		createAction:    "+",
+		updateAction:    "~",
+		replaceAction:   "-/+",
+		deleteAction:    "-",
+		unchangedAction: "=",
+		waitAction:      ">",
+	}
+
+	TextOutput = "text"
+	JSONOutput = "json"
+
+	// namespaces kind creates with every cluster
+	builtinNamespaces = map[string]bool{
+		"default":         true,
+		"kube-system":     true,
+		"kube-public":     true,
+		"kube-node-lease": true,
+	}
+)
+
+// Plan describes every change creating or applying a cluster makes
+type Plan struct {
+	Cluster       string          `json:"cluster"`
+	CreateCluster bool            `json:"createCluster"`
+	KindConfig    string          `json:"kindConfig,omitempty"`
+	Builds        []ImageBuild    `json:"builds,omitempty"`
+	Registry      *RegistryConfig `json:"registry,omitempty"`
+	CNI           *ReleaseChange  `json:"cni,omitempty"`
+	Images        []string        `json:"images,omitempty"`
+	Namespaces    []Change        `json:"namespaces"`
+	Resources     []Change        `json:"resources"`
+	HelmReleases  []ReleaseChange `json:"helmReleases"`
+}
+
+// Change is a single action on a kubernetes object
+type Change struct {
+	Action    string   `json:"action"`
+	Kind      string   `json:"kind"`
+	Namespace string   `json:"namespace,omitempty"`
+	Name      string   `json:"name"`
+	Diff      string   `json:"diff,omitempty"`
+	Object    resource `json:"object,omitempty"`
+
+	// why an object is recreated instead of updated
+	Reason string `json:"reason,omitempty"`
+
+	// live object the change was computed against
+	live resource
+
+	// host a tls secret's certificate is issued for
+	issueHost string
+}
+
+// hides the values of secrets when a change is printed as JSON
+func (c Change) MarshalJSON() ([]byte, error) {
+	type change Change
+	redacted := change(c)
+	if c.Object != nil {
+		redacted.Object = redact(c.Object)
+	}
+
+	return json.Marshal(redacted)
+}
+
+// ReleaseChange is a single action on a helm release
+type ReleaseChange struct {
+	Action string `json:"action"`
+	charts.HelmChart
+
+	// why a deployed release is upgraded
+	Reason string `json:"reason,omitempty"`
+}
+
+// builds the plan for a cluster, diffing against the live cluster if it exists
+func (c *Cluster) Plan(ctx context.Context) (*Plan, error) {
+	slog.Info("Checking if cluster exists")
+	clusterExists, err := c.clusterExists()
+	if err != nil {
+		err = fmt.Errorf("an error occurred while checking if cluster exists: %w", err)
+		slog.Error(err.Error())
+		return nil, err
+	}
+
+	return c.plan(ctx, clusterExists)
+}
+
+func (c *Cluster) plan(ctx context.Context, clusterExists bool) (*Plan, error) {
+	plan := &Plan{
+		Cluster:       c.Name,
+		CreateCluster: !clusterExists,
+		Namespaces:    []Change{},
+		Resources:     []Change{},
+		HelmReleases:  []ReleaseChange{},
+	}
+
+	config := &ClusterConfig{}
+	if c.ConfigFile != "" {
+		// validate cluster configuration
+		err := c.Validate(ctx)
+		if err != nil {
+			err = fmt.Errorf("an error occurred while validating cluster configuration: %w", err)
+			slog.Error(err.Error())
+			return nil, err
+		}
+
+		config, err = c.parseConfig()
+		if err != nil {
+			err = fmt.Errorf("error parsing cluster config: %w", err)
+			slog.Error(err.Error())
+			return nil, err
+		}
+
+		if !clusterExists {
+			plan.KindConfig, err = c.generateKindConfig()
+			if err != nil {
+				err = fmt.Errorf("an error occurred while generating kind config: %w", err)
+				slog.Error(err.Error())
+				return nil, err
+			}
+		}
+	}
+
+	// a cluster that does not exist yet has nothing live to diff against
+	var clientset *kubernetes.Clientset
+	if clusterExists {
+		var err error
+		clientset, err = createKubernetesClient(kindPrefix + c.Name)
+		if err != nil {
+			err = fmt.Errorf("error setting up clientset: %w", err)
+			slog.Error(err.Error())
+			return nil, err
+		}
+	}
+
+	var err error
+	plan.Builds, err = c.planBuilds(ctx, config)
+	if err != nil {
+		err = fmt.Errorf("error planning image builds: %w", err)
+		slog.Error(err.Error())
+		return nil, err
+	}
+
+	plan.Images, err = c.planImages(ctx, config)
+	if err != nil {
+		err = fmt.Errorf("error planning images: %w", err)
+		slog.Error(err.Error())
+		return nil, err
+	}
+
+	err = c.planApplications(ctx, clientset, config, plan)
+	if err != nil {
+		err = fmt.Errorf("error planning applications: %w", err)
+		slog.Error(err.Error())
+		return nil, err
+	}
+
+	err = c.planRegistry(ctx, clientset, config, plan)
+	if err != nil {
+		err = fmt.Errorf("error planning registry: %w", err)
+		slog.Error(err.Error())
+		return nil, err
+	}
+
+	if c.Prune && clusterExists {
+		err = c.planPrune(ctx, clientset, config, plan)
+		if err != nil {
+			err = fmt.Errorf("error planning prune: %w", err)
+			slog.Error(err.Error())
+			return nil, err
+		}
+	}
+
+	err = c.planReleases(config, plan)
+	if err != nil {
+		err = fmt.Errorf("error planning helm releases: %w", err)
+		slog.Error(err.Error())
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// adds the namespaces and objects of every application to the plan
+func (c *Cluster) planApplications(ctx context.Context, clientset *kubernetes.Clientset, config *ClusterConfig, plan *Plan) error {
+	apps, err := orderApplications(config.Applications)
+	if err != nil {
+		return err
+	}
+
+	namespaces := map[string]string{}
+	for _, app := range apps {
+		namespaces[app.Name] = app.Namespace
+	}
+
+	planned := map[string]bool{}
+	for _, app := range apps {
+		if !planned[app.Namespace] {
+			planned[app.Namespace] = true
+			change, err := planNamespace(ctx, clientset, namespace(app.Namespace, c.Name))
+			if err != nil {
+				return err
+			}
+			plan.Namespaces = append(plan.Namespaces, change)
+		}
+
+		resources, err := app.resources()
+		if err != nil {
+			return err
+		}
+
+		workload, err := app.workload()
+		if err != nil {
+			return err
+		}
+
+		// an application whose type changed has its previous workload removed first
+		kind := workload.GetObjectKind().GroupVersionKind().Kind
+		for _, other := range workloadKinds {
+			if other == kind || clientset == nil {
+				continue
+			}
+
+			client, err := clientFor(clientset, other, app.Namespace)
+			if err != nil {
+				return err
+			}
+
+			_, err = client.get(ctx, app.Name)
+			if apierrors.IsNotFound(err) {
+				continue
+			} else if err != nil {
+				return fmt.Errorf("error getting %s %s: %w", other, app.Name, err)
+			}
+
+			plan.Resources = append(plan.Resources, Change{
+				Action:    deleteAction,
+				Kind:      other,
+				Namespace: app.Namespace,
+				Name:      app.Name,
+			})
+		}
+
+		for _, dependency := range app.DependsOn {
+			plan.Resources = append(plan.Resources, Change{
+				Action:    waitAction,
+				Kind:      jobKind,
+				Namespace: namespaces[dependency],
+				Name:      dependency,
+			})
+		}
+
+		// ingress hosts are served with a certificate from the cluster's local authority
+		if app.Ingress != nil {
+			change, err := c.planTLSSecret(ctx, clientset, &app)
+			if err != nil {
+				return err
+			}
+			plan.Resources = append(plan.Resources, change)
+		}
+
+		for _, desired := range resources {
+			change, err := planChange(ctx, clientset, desired)
+			if err != nil {
+				return err
+			}
+			plan.Resources = append(plan.Resources, change)
+		}
+	}
+
+	return nil
+}
+
+// adds the helm releases to the plan
+func (c *Cluster) planReleases(config *ClusterConfig, plan *Plan) error {
+	releases, err := c.releases(config)
+	if err != nil {
+		return err
+	}
+
+	if cni := c.cniRelease(config); cni != nil {
+		change, err := c.planRelease(*cni, plan)
+		if err != nil {
+			return err
+		}
+		plan.CNI = &change
+	}
+
+	for _, chart := range releases {
+		change, err := c.planRelease(chart, plan)
+		if err != nil {
+			return err
+		}
+		plan.HelmReleases = append(plan.HelmReleases, change)
+	}
+
+	return nil
+}
+
+func (c *Cluster) planRelease(chart charts.HelmChart, plan *Plan) (ReleaseChange, error) {
+	var rel *helmrelease.Release
+	if !plan.CreateCluster {
+		var err error
+		rel, err = charts.DeployedRelease(kindPrefix+c.Name, chart)
+		if err != nil {
+			return ReleaseChange{}, err
+		}
+	}
+
+	return releaseChange(chart, rel)
+}
+
+// computes the change for a chart's release
+func releaseChange(chart charts.HelmChart, rel *helmrelease.Release) (ReleaseChange, error) {
+	change := ReleaseChange{
+		Action:    createAction,
+		HelmChart: chart,
+	}
+	if rel == nil {
+		return change, nil
+	}
+
+	reason, err := chart.Outdated(rel)
+	if err != nil {
+		return change, fmt.Errorf("error comparing release %s with its chart: %w", chart.Name, err)
+	}
+
+	change.Action = unchangedAction
+	if reason != "" {
+		change.Action = updateAction
+		change.Reason = reason
+	}
+	return change, nil
+}
+
+// computes the change for a live object
+func planChange(ctx context.Context, clientset *kubernetes.Clientset, desired resource) (Change, error) {
+	kind := desired.GetObjectKind().GroupVersionKind().Kind
+	change := Change{
+		Action:    createAction,
+		Kind:      kind,
+		Namespace: desired.GetNamespace(),
+		Name:      desired.GetName(),
+		Object:    desired,
+	}
+
+	if clientset != nil {
+		client, err := clientFor(clientset, kind, desired.GetNamespace())
+		if err != nil {
+			return change, err
+		}
+
+		live, err := client.get(ctx, desired.GetName())
+		if err != nil && !apierrors.IsNotFound(err) {
+			return change, fmt.Errorf("error getting %s %s: %w", kind, desired.GetName(), err)
+		} else if err == nil {
+			change.live = live
+			change.Action = updateAction
+			keepAllocatedNodePorts(desired, live)
+			if !resourceChanged(desired, live) {
+				change.Action = unchangedAction
+				return change, nil
+			}
+
+			// immutable field changes can only be made by recreating the object
+			err = checkUpdate(ctx, client, desired, live)
+			if apierrors.IsInvalid(err) {
+				change.Action = replaceAction
+				change.Reason = err.Error()
+			} else if err != nil {
+				return change, fmt.Errorf("error checking update of %s %s: %w", kind, desired.GetName(), err)
+			}
+		}
+	}
+
+	diff, err := diffResources(change.live, desired)
+	if err != nil {
+		return change, fmt.Errorf("error computing diff for %s %s: %w", kind, desired.GetName(), err)
+	}
+	change.Diff = diff
+
+	return change, nil
+}
+
+// computes the change for a namespace, leaving existing ones untouched
+func planNamespace(ctx context.Context, clientset *kubernetes.Clientset, desired resource) (Change, error) {
+	change := Change{
+		Action: createAction,
+		Kind:   namespaceKind,
+		Name:   desired.GetName(),
+		Object: desired,
+	}
+
+	if clientset == nil {
+		if builtinNamespaces[desired.GetName()] {
+			change.Action = unchangedAction
+		}
+		return change, nil
+	}
+
+	client, err := clientFor(clientset, namespaceKind, "")
+	if err != nil {
+		return change, err
+	}
+
+	_, err = client.get(ctx, desired.GetName())
+	if err == nil {
+		change.Action = unchangedAction
+	} else if !apierrors.IsNotFound(err) {
+		return change, fmt.Errorf("error getting namespace %s: %w", desired.GetName(), err)
+	}
+
+	return change, nil
+}
+
+// carries out every change in the plan, phase by phase
+func (c *Cluster) execute(ctx context.Context, plan *Plan, cp *checkpoint) error {
+	run := newPhaseRunner(cp)
+
+	// build before provisioning so a broken build leaves no cluster behind
+	if len(plan.Builds) > 0 {
+		err := run(buildPhase, func() error {
+			return buildImages(ctx, plan.Builds)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if plan.CreateCluster {
+		err := run(provisionPhase, func() error {
+			slog.Info("Starting creation of cluster. Please note this may take some time")
+			if plan.KindConfig == "" {
+				return c.createWithName()
+			}
+			return c.createWithConfig(plan.KindConfig)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if plan.Registry != nil {
+		// the kind network only exists once a cluster is provisioned
+		err := run(registryPhase, func() error {
+			err := plan.Registry.ensure(ctx)
+			if err != nil {
+				return fmt.Errorf("error starting registry %s: %w", plan.Registry.name(), err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	kubeContext := kindPrefix + c.Name
+	if plan.CNI != nil && plan.CNI.Action != unchangedAction {
+		// pods cannot start until the CNI plugin is installed
+		err := run(cniPhase, func() error {
+			err := executeRelease(ctx, kubeContext, *plan.CNI)
+			if err != nil {
+				return fmt.Errorf("error installing cni %s: %w", plan.CNI.Name, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(plan.Images) > 0 {
+		err := run(imagesPhase, func() error {
+			return c.loadImages(ctx, plan.Images)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var clientset *kubernetes.Clientset
+	err := run(namespacesPhase, func() error {
+		var err error
+		clientset, err = createKubernetesClient(kubeContext)
+		if err != nil {
+			return fmt.Errorf("error setting up clientset: %w", err)
+		}
+
+		slog.Info("Deploying user applications to cluster")
+		for _, change := range plan.Namespaces {
+			if change.Action == deleteAction {
+				continue
+			}
+
+			err = executeChange(ctx, clientset, change, c.timeout())
+			if err != nil {
+				return fmt.Errorf("error applying namespace %s: %w", change.Name, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = run(applicationsPhase, func() error {
+		for _, change := range plan.Resources {
+			if change.issueHost != "" {
+				err := c.issueCertificate(change)
+				if err != nil {
+					return fmt.Errorf("error issuing certificate for %s: %w", change.issueHost, err)
+				}
+			}
+
+			err := executeChange(ctx, clientset, change, c.timeout())
+			if err != nil {
+				return fmt.Errorf("error applying %s %s/%s: %w", change.Kind, change.Namespace, change.Name, err)
+			}
+		}
+
+		return deleteNamespaces(ctx, clientset, plan, c.timeout())
+	})
+	if err != nil {
+		return err
+	}
+
+	err = run(rolloutPhase, func() error {
+		err := waitForRollouts(ctx, clientset, plan, c.resuming(cp), c.timeout())
+		if err != nil {
+			return fmt.Errorf("error waiting for applications to roll out: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	slog.Info("Successfully deployed all applications to cluster")
+
+	slog.Info("Applying default resources to cluster")
+	for _, release := range plan.HelmReleases {
+		if release.Action == unchangedAction {
+			continue
+		}
+
+		err = run(chartPhase(release.HelmChart), func() error {
+			err := executeRelease(ctx, kubeContext, release)
+			if err != nil {
+				return fmt.Errorf("error while applying default resources: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deletes the namespaces the plan removes, skipping any that are not empty
+func deleteNamespaces(ctx context.Context, clientset *kubernetes.Clientset, plan *Plan, timeout time.Duration) error {
+	for _, change := range plan.Namespaces {
+		if change.Action != deleteAction {
+			continue
+		}
+
+		empty, err := namespaceEmpty(ctx, clientset, change.Name)
+		if err != nil {
+			return fmt.Errorf("error checking contents of namespace %s: %w", change.Name, err)
+		} else if !empty {
+			slog.Info("Namespace is no longer declared but is not empty. Skipping deletion", "namespace", change.Name)
+			continue
+		}
+
+		err = executeChange(ctx, clientset, change, timeout)
+		if err != nil {
+			return fmt.Errorf("error deleting namespace %s: %w", change.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func executeChange(ctx context.Context, clientset *kubernetes.Clientset, change Change, timeout time.Duration) error {
+	client, err := clientFor(clientset, change.Kind, change.Namespace)
+	if err != nil {
+		return err
+	}
+
+	switch change.Action {
+	case createAction:
+		slog.Info("Creating resource", "kind", change.Kind, "namespace", change.Namespace, "name", change.Name)
+		return client.create(ctx, change.Object)
+	case updateAction:
+		slog.Info("Updating resource", "kind", change.Kind, "namespace", change.Namespace, "name", change.Name)
+		return updateResource(ctx, client, change.Object, change.live)
+	case replaceAction:
+		slog.Info("Replacing resource", "kind", change.Kind, "namespace", change.Namespace, "name", change.Name)
+		return replaceResource(ctx, client, change.Object)
+	case deleteAction:
+		slog.Info("Deleting resource", "kind", change.Kind, "namespace", change.Namespace, "name", change.Name)
+		return deleteAndWait(ctx, client, change.Name)
+	case waitAction:
+		return waitForJob(ctx, clientset, change.Namespace, change.Name, timeout)
+	default:
+		return nil
+	}
+}
+
+// installs a release, or upgrades one that is already deployed
+func executeRelease(ctx context.Context, kubeContext string, change ReleaseChange) error {
+	if change.Action == updateAction {
+		slog.Info("Upgrading release", "release", change.Name, "reason", change.Reason)
+		return charts.Upgrade(ctx, kubeContext, change.HelmChart)
+	}
+	return charts.Install(ctx, kubeContext, change.HelmChart)
+}
+
+// writes the plan in the given output format
+func (p *Plan) Write(w io.Writer, format string) error {
+	switch format {
+	case "", TextOutput:
+		_, err := io.WriteString(w, p.text())
+		return err
+	case JSONOutput:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(p)
+	default:
+		return status.Errorf(codes.InvalidArgument, "output format must be one of [text, json]")
+	}
+}
+
+// renders the plan as a human readable diff
+func (p *Plan) text() string {
+	var b strings.Builder
+	if p.CreateCluster {
+		fmt.Fprintf(&b, "Cluster %s will be created\n", p.Cluster)
+		if p.KindConfig != "" {
+			b.WriteString("\nKind config:\n")
+			b.WriteString(indent(p.KindConfig, "    "))
+		}
+	} else {
+		fmt.Fprintf(&b, "Cluster %s already exists and will be updated in place\n", p.Cluster)
+	}
+
+	if len(p.Builds) > 0 {
+		b.WriteString("\nImage builds:\n")
+		for _, build := range p.Builds {
+			fmt.Fprintf(&b, "  %s %s (%s, %s)\n", actionSymbols[build.Action], build.Image, build.Application, filepath.Join(build.Context, build.Dockerfile))
+		}
+	}
+
+	if p.Registry != nil {
+		fmt.Fprintf(&b, "\nRegistry:\n  %s (%s)\n", p.Registry.name(), p.Registry.host())
+	}
+
+	counts := map[string]int{}
+	if p.CNI != nil {
+		counts[p.CNI.Action]++
+		b.WriteString("\nCNI:\n")
+		p.CNI.writeText(&b)
+	}
+
+	if len(p.Images) > 0 {
+		b.WriteString("\nImages loaded into nodes:\n")
+		for _, image := range p.Images {
+			fmt.Fprintf(&b, "  %s\n", image)
+		}
+	}
+
+	for _, section := range []struct {
+		title   string
+		changes []Change
+	}{
+		{"Namespaces", p.Namespaces},
+		{"Resources", p.Resources},
+	} {
+		fmt.Fprintf(&b, "\n%s:\n", section.title)
+		if len(section.changes) == 0 {
+			b.WriteString("  (none)\n")
+		}
+
+		for _, change := range section.changes {
+			if change.Action != waitAction {
+				counts[change.Action]++
+			}
+			name := change.Name
+			if change.Namespace != "" {
+				name = change.Namespace + "/" + change.Name
+			}
+
+			fmt.Fprintf(&b, "  %s %s %s", actionSymbols[change.Action], change.Kind, name)
+			if change.Kind == namespaceKind && change.Action == deleteAction {
+				b.WriteString(" (if empty)")
+			} else if change.Action == waitAction {
+				b.WriteString(" (wait for completion)")
+			} else if change.Action == replaceAction {
+				fmt.Fprintf(&b, " (recreated: %s)", change.Reason)
+			}
+			b.WriteString("\n")
+			if change.Diff != "" {
+				b.WriteString(indent(change.Diff, "      "))
+			}
+		}
+	}
+
+	b.WriteString("\nHelm releases:\n")
+	if len(p.HelmReleases) == 0 {
+		b.WriteString("  (none)\n")
+	}
+	for _, release := range p.HelmReleases {
+		counts[release.Action]++
+		release.writeText(&b)
+	}
+
+	fmt.Fprintf(&b, "\nPlan: %d to create, %d to update, %d to replace, %d to delete, %d unchanged\n",
+		counts[createAction], counts[updateAction], counts[replaceAction], counts[deleteAction], counts[unchangedAction])
+	return b.String()
+}
+
+func (r *ReleaseChange) writeText(b *strings.Builder) {
+	fmt.Fprintf(b, "  %s %s/%s (%s)", actionSymbols[r.Action], r.Namespace, r.Name, r.Reference())
+	if r.Reason != "" {
+		fmt.Fprintf(b, ": %s", r.Reason)
+	}
+	b.WriteString("\n")
+}
+
+func indent(s string, prefix string) string {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	return prefix + strings.Join(lines, "\n"+prefix) + "\n"
+}
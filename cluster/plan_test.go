@@ -0,0 +1,237 @@
+package cluster
+
+import (
+	"skillet/skillet-kind/charts"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// fills in the probe fields the api server defaults
+func defaultProbe(probe *apiv1.Probe) {
+	if probe == nil {
+		return
+	}
+	if probe.HTTPGet != nil && probe.HTTPGet.Scheme == "" {
+		probe.HTTPGet.Scheme = apiv1.URISchemeHTTP
+	}
+	if probe.PeriodSeconds == 0 {
+		probe.PeriodSeconds = 10
+	}
+	if probe.TimeoutSeconds == 0 {
+		probe.TimeoutSeconds = 1
+	}
+	if probe.SuccessThreshold == 0 {
+		probe.SuccessThreshold = 1
+	}
+	if probe.FailureThreshold == 0 {
+		probe.FailureThreshold = 3
+	}
+}
+
+func TestPlanProbesUnchangedAfterDefaulting(t *testing.T) {
+	app := Application{
+		Name:      "web",
+		Namespace: "default",
+		Type:      deploymentType,
+		Image:     "nginx:1.25",
+		Replicas:  1,
+		Ports:     []Port{{ContainerPort: 80}},
+
+		LivenessProbe:  &Probe{HTTP: &HTTPProbe{Path: "/healthz"}},
+		ReadinessProbe: &Probe{TCP: &TCPProbe{}, PeriodSeconds: 5},
+		StartupProbe:   &Probe{Exec: []string{"true"}, FailureThreshold: 30},
+	}
+
+	desired := app.deployment()
+	live := desired.DeepCopy()
+	live.TypeMeta = appsv1.Deployment{}.TypeMeta
+	container := &live.Spec.Template.Spec.Containers[0]
+	container.TerminationMessagePath = apiv1.TerminationMessagePathDefault
+	container.TerminationMessagePolicy = apiv1.TerminationMessageReadFile
+	defaultProbe(container.LivenessProbe)
+	defaultProbe(container.ReadinessProbe)
+	defaultProbe(container.StartupProbe)
+
+	if resourceChanged(desired, live) {
+		t.Fatalf("deployment with probes changed after server defaulting")
+	}
+
+	container.ReadinessProbe.PeriodSeconds = 10
+	if !resourceChanged(desired, live) {
+		t.Fatalf("expected a changed probe period to be planned as a change")
+	}
+}
+
+func TestPlanServiceUnchangedAfterNodePortAllocation(t *testing.T) {
+	app := Application{
+		Name:      "web",
+		Namespace: "default",
+		Ports:     []Port{{ContainerPort: 80, ServiceType: string(apiv1.ServiceTypeNodePort)}},
+	}
+
+	desired := app.service()
+	live := desired.DeepCopy()
+	live.Spec.Ports[0].NodePort = 31234
+
+	keepAllocatedNodePorts(desired, live)
+	if resourceChanged(desired, live) {
+		t.Fatalf("service changed after node port allocation")
+	}
+}
+
+func deployedRelease(version string) *helmrelease.Release {
+	return &helmrelease.Release{
+		Chart: &chart.Chart{Metadata: &chart.Metadata{Name: "prometheus", Version: version}},
+		Info:  &helmrelease.Info{Status: helmrelease.StatusDeployed},
+	}
+}
+
+func TestPlanReleaseVersion(t *testing.T) {
+	tests := []struct {
+		version   string
+		installed string
+		action    string
+	}{
+		{version: "25.13.0", installed: "25.13.0", action: unchangedAction},
+		{version: "^25.0.0", installed: "25.13.0", action: unchangedAction},
+		{version: "", installed: "25.13.0", action: unchangedAction},
+		{version: "25.14.0", installed: "25.13.0", action: updateAction},
+		{version: "^26.0.0", installed: "25.13.0", action: updateAction},
+	}
+
+	for _, test := range tests {
+		helmChart := charts.HelmChart{
+			Name:      "prometheus",
+			Namespace: "prometheus",
+			URL:       "https://prometheus-community.github.io/helm-charts",
+			Chart:     "prometheus",
+			Version:   test.version,
+		}
+
+		change, err := releaseChange(helmChart, deployedRelease(test.installed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if change.Action != test.action {
+			t.Errorf("version %q with %s installed: expected %s, got %s", test.version, test.installed, test.action, change.Action)
+		}
+	}
+
+	change, err := releaseChange(charts.HelmChart{Name: "prometheus", Chart: "prometheus"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Action != createAction {
+		t.Errorf("expected a release that is not deployed to be created, got %s", change.Action)
+	}
+}
+
+func TestPlanReleaseValues(t *testing.T) {
+	installed := map[string]interface{}{
+		"server": map[string]interface{}{
+			"persistentVolume": map[string]interface{}{"enabled": false},
+			"replicaCount":     float64(1),
+		},
+	}
+
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		set    []string
+		action string
+	}{
+		{
+			name: "same values",
+			values: map[string]interface{}{
+				"server": map[string]interface{}{
+					"persistentVolume": map[string]interface{}{"enabled": false},
+					"replicaCount":     1,
+				},
+			},
+			action: unchangedAction,
+		},
+		{
+			name: "same values from --set",
+			values: map[string]interface{}{
+				"server": map[string]interface{}{"replicaCount": 1},
+			},
+			set:    []string{"server.persistentVolume.enabled=false"},
+			action: unchangedAction,
+		},
+		{
+			name: "changed inline value",
+			values: map[string]interface{}{
+				"server": map[string]interface{}{
+					"persistentVolume": map[string]interface{}{"enabled": true},
+					"replicaCount":     1,
+				},
+			},
+			action: updateAction,
+		},
+		{
+			name: "changed by --set",
+			values: map[string]interface{}{
+				"server": map[string]interface{}{
+					"persistentVolume": map[string]interface{}{"enabled": false},
+					"replicaCount":     1,
+				},
+			},
+			set:    []string{"server.replicaCount=2"},
+			action: updateAction,
+		},
+		{
+			name:   "values removed",
+			action: updateAction,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			helmChart := charts.HelmChart{
+				Name:      "prometheus",
+				Namespace: "prometheus",
+				URL:       "https://prometheus-community.github.io/helm-charts",
+				Chart:     "prometheus",
+				Version:   "25.13.0",
+				Values:    test.values,
+				Set:       test.set,
+			}
+
+			rel := deployedRelease("25.13.0")
+			rel.Config = installed
+			change, err := releaseChange(helmChart, rel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if change.Action != test.action {
+				t.Errorf("expected %s, got %s", test.action, change.Action)
+			}
+		})
+	}
+}
+
+func TestRolloutChangesWhenResuming(t *testing.T) {
+	plan := &Plan{Resources: []Change{
+		{Action: unchangedAction, Kind: deploymentKind, Namespace: "default", Name: "applied-earlier"},
+		{Action: createAction, Kind: deploymentKind, Namespace: "default", Name: "applied-now"},
+		{Action: deleteAction, Kind: deploymentKind, Namespace: "default", Name: "removed"},
+		{Action: unchangedAction, Kind: configMapKind, Namespace: "default", Name: "config"},
+	}}
+
+	// the earlier attempt failed partway through the applications phase
+	cp := &checkpoint{Completed: []string{buildPhase, provisionPhase, namespacesPhase}}
+
+	resumed := &Cluster{Resume: true}
+	if changes := rolloutChanges(plan, resumed.resuming(cp)); len(changes) != 2 {
+		t.Fatalf("expected a resumed creation to wait on both kept workloads, got %d", len(changes))
+	}
+
+	applied := &Cluster{}
+	if changes := rolloutChanges(plan, applied.resuming(nil)); len(changes) != 1 || changes[0].Name != "applied-now" {
+		t.Fatalf("expected apply to only wait on the created workload, got %v", changes)
+	}
+}
@@ -0,0 +1,156 @@
+package cluster
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	managedByLabel   = "app.kubernetes.io/managed-by"
+	managedByValue   = "skillet"
+	clusterLabel     = "skillet.io/cluster"
+	applicationLabel = "skillet.io/application"
+	configHashLabel  = "skillet.io/config-hash"
+
+	configHashLength = 16
+
+	rootCAConfigMap = "kube-root-ca.crt"
+)
+
+// labels stamped on every object skillet creates for a cluster
+func ownerLabels(clusterName string) map[string]string {
+	return map[string]string{
+		managedByLabel: managedByValue,
+		clusterLabel:   clusterName,
+	}
+}
+
+// label selector matching every object skillet created for a cluster
+func ownerSelector(clusterName string) string {
+	return labels.SelectorFromSet(ownerLabels(clusterName)).String()
+}
+
+// labels stamped on every object skillet creates for an application
+func (a *Application) ownerLabels() map[string]string {
+	ownerLabels := ownerLabels(a.cluster)
+	ownerLabels[applicationLabel] = a.Name
+	ownerLabels[configHashLabel] = a.configHash()
+	return ownerLabels
+}
+
+// hash of the application's configuration, used to trace objects back to the config that produced them
+func (a *Application) configHash() string {
+	// an application only holds plain data, so marshalling cannot fail
+	data, _ := json.Marshal(a)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:configHashLength]
+}
+
+// adds the deletion of skillet-owned objects and namespaces that are no longer declared to the plan
+func (c *Cluster) planPrune(ctx context.Context, clientset kubernetes.Interface, config *ClusterConfig, plan *Plan) error {
+	// objects that are declared, or already deleted by the plan such as the previous workload of
+	// an application whose type changed
+	planned := map[string]bool{}
+	for _, change := range plan.Resources {
+		if change.Action != waitAction {
+			planned[resourceKey(change.Kind, change.Namespace, change.Name)] = true
+		}
+	}
+
+	declaredNamespaces := map[string]bool{}
+	for _, app := range config.Applications {
+		declaredNamespaces[app.Namespace] = true
+	}
+
+	for _, kind := range managedKinds {
+		client, err := clientFor(clientset, kind, metav1.NamespaceAll)
+		if err != nil {
+			return err
+		}
+
+		owned, err := client.list(ctx, ownerSelector(c.Name))
+		if err != nil {
+			return fmt.Errorf("error listing %s objects: %w", kind, err)
+		}
+
+		for _, obj := range owned {
+			if planned[resourceKey(kind, obj.GetNamespace(), obj.GetName())] {
+				continue
+			}
+
+			plan.Resources = append(plan.Resources, Change{
+				Action:    deleteAction,
+				Kind:      kind,
+				Namespace: obj.GetNamespace(),
+				Name:      obj.GetName(),
+			})
+		}
+	}
+
+	client, err := clientFor(clientset, namespaceKind, "")
+	if err != nil {
+		return err
+	}
+
+	namespaces, err := client.list(ctx, ownerSelector(c.Name))
+	if err != nil {
+		return fmt.Errorf("error listing namespaces: %w", err)
+	}
+
+	for _, ns := range namespaces {
+		if declaredNamespaces[ns.GetName()] {
+			continue
+		}
+
+		plan.Namespaces = append(plan.Namespaces, Change{
+			Action: deleteAction,
+			Kind:   namespaceKind,
+			Name:   ns.GetName(),
+		})
+	}
+
+	return nil
+}
+
+// reports whether a namespace has no pods or skillet-managed kinds of objects left in it
+func namespaceEmpty(ctx context.Context, clientset kubernetes.Interface, namespace string) (bool, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return false, err
+	} else if len(pods.Items) > 0 {
+		return false, nil
+	}
+
+	for _, kind := range managedKinds {
+		client, err := clientFor(clientset, kind, namespace)
+		if err != nil {
+			return false, err
+		}
+
+		objs, err := client.list(ctx, "")
+		if err != nil {
+			return false, err
+		}
+
+		for _, obj := range objs {
+			// kubernetes publishes its root certificate into every namespace
+			if kind == configMapKind && obj.GetName() == rootCAConfigMap {
+				continue
+			}
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func resourceKey(kind string, namespace string, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
@@ -0,0 +1,110 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func objectMeta(namespace string, name string, labels map[string]string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels}
+}
+
+func deleted(plan *Plan) map[string]int {
+	keys := map[string]int{}
+	for _, change := range append(plan.Resources, plan.Namespaces...) {
+		if change.Action == deleteAction {
+			keys[resourceKey(change.Kind, change.Namespace, change.Name)]++
+		}
+	}
+	return keys
+}
+
+func TestPlanPrune(t *testing.T) {
+	owned := ownerLabels("dev")
+	objects := []runtime.Object{
+		&apiv1.Namespace{ObjectMeta: objectMeta("", "web", owned)},
+		&apiv1.Namespace{ObjectMeta: objectMeta("", "removed", owned)},
+		&apiv1.Namespace{ObjectMeta: objectMeta("", "unowned", nil)},
+		&appsv1.Deployment{ObjectMeta: objectMeta("web", "web", owned)},
+		&appsv1.Deployment{ObjectMeta: objectMeta("web", "retyped", owned)},
+		&appsv1.Deployment{ObjectMeta: objectMeta("removed", "api", owned)},
+		&appsv1.Deployment{ObjectMeta: objectMeta("web", "other-cluster", ownerLabels("staging"))},
+		&appsv1.Deployment{ObjectMeta: objectMeta("web", "unlabelled", nil)},
+		&apiv1.ConfigMap{ObjectMeta: objectMeta("removed", "api", owned)},
+	}
+
+	config := &ClusterConfig{Applications: []Application{{Name: "web", Namespace: "web"}}}
+	plan := &Plan{Resources: []Change{
+		{Action: unchangedAction, Kind: deploymentKind, Namespace: "web", Name: "web"},
+		// the application became a statefulset, so its deployment is already deleted
+		{Action: deleteAction, Kind: deploymentKind, Namespace: "web", Name: "retyped"},
+		{Action: createAction, Kind: statefulsetKind, Namespace: "web", Name: "retyped"},
+	}}
+
+	c := &Cluster{Name: "dev"}
+	err := c.planPrune(context.Background(), fake.NewSimpleClientset(objects...), config, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int{
+		resourceKey(deploymentKind, "web", "retyped"): 1,
+		resourceKey(deploymentKind, "removed", "api"): 1,
+		resourceKey(configMapKind, "removed", "api"):  1,
+		resourceKey(namespaceKind, "", "removed"):     1,
+	}
+	actual := deleted(plan)
+	if len(actual) != len(expected) {
+		t.Fatalf("expected deletions %v, got %v", expected, actual)
+	}
+	for key, count := range expected {
+		if actual[key] != count {
+			t.Errorf("expected %s to be deleted %d times, got %d", key, count, actual[key])
+		}
+	}
+}
+
+func TestNamespaceEmpty(t *testing.T) {
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		empty   bool
+	}{
+		{
+			name:    "root certificate only",
+			objects: []runtime.Object{&apiv1.ConfigMap{ObjectMeta: objectMeta("web", rootCAConfigMap, nil)}},
+			empty:   true,
+		},
+		{
+			name:    "config map left",
+			objects: []runtime.Object{&apiv1.ConfigMap{ObjectMeta: objectMeta("web", "settings", nil)}},
+		},
+		{
+			name:    "pod left",
+			objects: []runtime.Object{&apiv1.Pod{ObjectMeta: objectMeta("web", "web-0", nil)}},
+		},
+		{
+			name:    "objects in other namespaces",
+			objects: []runtime.Object{&appsv1.Deployment{ObjectMeta: objectMeta("api", "api", nil)}},
+			empty:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			empty, err := namespaceEmpty(context.Background(), fake.NewSimpleClientset(test.objects...), "web")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if empty != test.empty {
+				t.Fatalf("expected empty to be %v, got %v", test.empty, empty)
+			}
+		})
+	}
+}
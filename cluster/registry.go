@@ -0,0 +1,322 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	osexec "os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	kind_cluster "sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/exec"
+)
+
+var (
+	defaultRegistryName  = "kind-registry"
+	defaultRegistryPort  = 5001
+	defaultRegistryImage = "registry:2"
+	registryInternalPort = "5000/tcp"
+
+	// the docker network kind puts the nodes of every cluster on
+	kindNetwork          = "kind"
+	kindNetworkEnvVar    = "KIND_EXPERIMENTAL_DOCKER_NETWORK"
+	containerNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+	// the ConfigMap tools discover a local registry through, see KEP-1755
+	localRegistryConfigMap = "local-registry-hosting"
+	localRegistryKey       = "localRegistryHosting.v1"
+	localRegistryHelp      = "https://kind.sigs.k8s.io/docs/user/local-registry/"
+)
+
+// a local image registry the cluster's nodes pull from
+type RegistryConfig struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	Name    string `yaml:"name" json:"name"`
+	Port    int    `yaml:"port" json:"port"`
+}
+
+func (r *RegistryConfig) enabled() bool {
+	return r != nil && r.Enabled
+}
+
+func (r *RegistryConfig) name() string {
+	if r == nil || r.Name == "" {
+		return defaultRegistryName
+	}
+	return r.Name
+}
+
+func (r *RegistryConfig) port() int {
+	if r == nil || r.Port == 0 {
+		return defaultRegistryPort
+	}
+	return r.Port
+}
+
+// the address images are pushed to from the host, and pulled from by the nodes
+func (r *RegistryConfig) host() string {
+	return fmt.Sprintf("localhost:%d", r.port())
+}
+
+// checks if an image is pulled from the registry
+func (r *RegistryConfig) hosts(image string) bool {
+	return r.enabled() && strings.HasPrefix(image, r.host()+"/")
+}
+
+// points containerd on every node at the registry container
+func (r *RegistryConfig) containerdPatches() []string {
+	if !r.enabled() {
+		return []string{}
+	}
+
+	return []string{fmt.Sprintf(`[plugins."io.containerd.grpc.v1.cri".registry.mirrors."%s"]
+  endpoint = ["http://%s:5000"]
+`, r.host(), r.name())}
+}
+
+// counts the nodes of a cluster mirroring the registry, out of all its nodes
+func (r *RegistryConfig) mirroredNodes(clusterName string) (int, int, error) {
+	provider := kind_cluster.NewProvider()
+	nodeList, err := provider.ListNodes(clusterName)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error listing nodes of cluster %s: %w", clusterName, err)
+	}
+
+	// matches mirrors set by skillet and hosts.toml files from the kind registry guide
+	endpoint := fmt.Sprintf("http://%s:5000", r.name())
+	mirrored := 0
+	for _, node := range nodeList {
+		err := node.Command("grep", "-rqsF", endpoint, "/etc/containerd").Run()
+		if err == nil {
+			mirrored++
+			continue
+		}
+
+		// grep exits with 1 when nothing matched
+		var runErr *exec.RunError
+		var exitErr *osexec.ExitError
+		if errors.As(err, &runErr) && errors.As(runErr.Inner, &exitErr) && exitErr.ExitCode() == 1 {
+			continue
+		}
+		return 0, 0, fmt.Errorf("error checking containerd config of node %s: %w", node.String(), err)
+	}
+
+	return mirrored, len(nodeList), nil
+}
+
+// the ConfigMap publishing the registry's address
+func (r *RegistryConfig) configMap(clusterName string) (*apiv1.ConfigMap, error) {
+	hosting, err := yaml.Marshal(map[string]string{
+		"host": r.host(),
+		"help": localRegistryHelp,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiv1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       configMapKind,
+			APIVersion: apiv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      localRegistryConfigMap,
+			Namespace: metav1.NamespacePublic,
+			Labels:    ownerLabels(clusterName),
+		},
+		Data: map[string]string{
+			localRegistryKey: string(hosting),
+		},
+	}, nil
+}
+
+func newDockerClient() (*client.Client, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("error creating docker client: %w", err)
+	}
+	return cli, nil
+}
+
+func kindNetworkName() string {
+	if network := os.Getenv(kindNetworkEnvVar); network != "" {
+		return network
+	}
+	return kindNetwork
+}
+
+// starts or reuses the registry container and connects it to the kind network
+func (r *RegistryConfig) ensure(ctx context.Context) error {
+	cli, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	info, err := cli.ContainerInspect(ctx, r.name())
+	if client.IsErrNotFound(err) {
+		slog.Info("Starting local registry", "registry", r.name(), "host", r.host())
+		info, err = r.create(ctx, cli)
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return fmt.Errorf("error inspecting registry %s: %w", r.name(), err)
+	} else {
+		slog.Info("Reusing local registry", "registry", r.name(), "host", r.host())
+		if err := r.checkPort(info); err != nil {
+			return err
+		}
+	}
+
+	if !info.State.Running {
+		err = cli.ContainerStart(ctx, info.ID, types.ContainerStartOptions{})
+		if err != nil {
+			return fmt.Errorf("error starting registry %s: %w", r.name(), err)
+		}
+	}
+
+	network := kindNetworkName()
+	if _, ok := info.NetworkSettings.Networks[network]; !ok {
+		err = cli.NetworkConnect(ctx, network, info.ID, nil)
+		if err != nil {
+			return fmt.Errorf("error connecting registry %s to network %s: %w", r.name(), network, err)
+		}
+	}
+
+	return nil
+}
+
+// creates the registry container, publishing it on the host's loopback interface only
+func (r *RegistryConfig) create(ctx context.Context, cli *client.Client) (types.ContainerJSON, error) {
+	reader, err := cli.ImagePull(ctx, defaultRegistryImage, types.ImagePullOptions{})
+	if err != nil {
+		return types.ContainerJSON{}, fmt.Errorf("error pulling registry image %s: %w", defaultRegistryImage, err)
+	}
+	_, err = io.Copy(io.Discard, reader)
+	reader.Close()
+	if err != nil {
+		return types.ContainerJSON{}, fmt.Errorf("error pulling registry image %s: %w", defaultRegistryImage, err)
+	}
+
+	port := nat.Port(registryInternalPort)
+	created, err := cli.ContainerCreate(ctx,
+		&container.Config{
+			Image:        defaultRegistryImage,
+			ExposedPorts: nat.PortSet{port: struct{}{}},
+		},
+		&container.HostConfig{
+			PortBindings: nat.PortMap{
+				port: []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: strconv.Itoa(r.port())}},
+			},
+			RestartPolicy: container.RestartPolicy{Name: "always"},
+		},
+		nil, nil, r.name())
+	if err != nil {
+		return types.ContainerJSON{}, fmt.Errorf("error creating registry %s: %w", r.name(), err)
+	}
+
+	return cli.ContainerInspect(ctx, created.ID)
+}
+
+// checks a reused registry is published on the configured port
+func (r *RegistryConfig) checkPort(info types.ContainerJSON) error {
+	if info.HostConfig == nil {
+		return nil
+	}
+
+	for _, binding := range info.HostConfig.PortBindings[nat.Port(registryInternalPort)] {
+		if binding.HostPort == strconv.Itoa(r.port()) {
+			return nil
+		}
+	}
+
+	return status.Errorf(codes.FailedPrecondition, "container %s already exists but is not published on port %d. Set the registry port to match it, or choose another registry name", r.name(), r.port())
+}
+
+// removes the registry container, along with the images pushed to it
+func removeRegistry(ctx context.Context, name string) error {
+	cli, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	err = cli.ContainerRemove(ctx, name, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
+	if client.IsErrNotFound(err) {
+		slog.Info("Registry does not exist. Skipping removal", "registry", name)
+		return nil
+	}
+	return err
+}
+
+// adds the registry and the ConfigMap advertising it to the plan
+func (c *Cluster) planRegistry(ctx context.Context, clientset *kubernetes.Clientset, config *ClusterConfig, plan *Plan) error {
+	if !config.Registry.enabled() {
+		return nil
+	}
+	plan.Registry = config.Registry
+
+	// the mirror is only written to containerd when the nodes are provisioned
+	if clientset != nil {
+		mirrored, nodes, err := config.Registry.mirroredNodes(c.Name)
+		if err != nil {
+			return fmt.Errorf("error checking the registry mirror of cluster %s: %w", c.Name, err)
+		}
+		if mirrored < nodes {
+			return status.Errorf(codes.FailedPrecondition, "cluster %s was created without a mirror for registry %s, recreate the cluster to use it", c.Name, config.Registry.name())
+		}
+	}
+
+	configMap, err := config.Registry.configMap(c.Name)
+	if err != nil {
+		return err
+	}
+
+	change, err := planChange(ctx, clientset, configMap)
+	if err != nil {
+		return err
+	}
+	plan.Resources = append(plan.Resources, change)
+
+	return nil
+}
+
+func validateRegistry(config *ClusterConfig) error {
+	registry := config.Registry
+	if !registry.enabled() {
+		return nil
+	}
+
+	if !containerNamePattern.MatchString(registry.name()) {
+		return status.Errorf(codes.FailedPrecondition, "registry name %q must only contain letters, numbers, underscores, periods and hyphens", registry.name())
+	}
+
+	if registry.Port < 0 || registry.Port > 65535 {
+		return status.Errorf(codes.FailedPrecondition, "registry port %d must be between 1 and 65535", registry.Port)
+	}
+
+	// containerd ignores mirrors once a hosts directory is configured
+	for i, patch := range config.ContainerdConfigPatches {
+		if strings.Contains(patch, "config_path") {
+			return status.Errorf(codes.FailedPrecondition, "containerd config patch %d sets a registry config_path, which would override the mirror of the local registry", i)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	namespaceKind   = "Namespace"
+	deploymentKind  = "Deployment"
+	daemonsetKind   = "DaemonSet"
+	statefulsetKind = "StatefulSet"
+	jobKind         = "Job"
+	cronjobKind     = "CronJob"
+	serviceKind     = "Service"
+	ingressKind     = "Ingress"
+	secretKind      = "Secret"
+	configMapKind   = "ConfigMap"
+
+	// kinds an application can be deployed as
+	workloadKinds = []string{deploymentKind, daemonsetKind, statefulsetKind, jobKind, cronjobKind}
+
+	// namespaced kinds skillet creates for applications, in the order they are created
+	managedKinds = []string{configMapKind, secretKind, serviceKind, ingressKind, deploymentKind, daemonsetKind, statefulsetKind, jobKind, cronjobKind}
+
+	deletionPollInterval = time.Second
+	deletionTimeout      = 2 * time.Minute
+)
+
+// resource is a kubernetes object managed by skillet
+type resource interface {
+	metav1.Object
+	runtime.Object
+}
+
+// resourceClient performs operations on a single kind of resource in a namespace
+type resourceClient interface {
+	get(ctx context.Context, name string) (resource, error)
+	create(ctx context.Context, obj resource) error
+	update(ctx context.Context, obj resource, dryRun bool) error
+	delete(ctx context.Context, name string) error
+	list(ctx context.Context, selector string) ([]resource, error)
+}
+
+// typedInterface is the subset of a typed client-go client used by skillet
+type typedInterface[T resource, L runtime.Object] interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (T, error)
+	Create(ctx context.Context, obj T, opts metav1.CreateOptions) (T, error)
+	Update(ctx context.Context, obj T, opts metav1.UpdateOptions) (T, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	List(ctx context.Context, opts metav1.ListOptions) (L, error)
+}
+
+type typedClient[T resource, L runtime.Object] struct {
+	client typedInterface[T, L]
+}
+
+func (t typedClient[T, L]) get(ctx context.Context, name string) (resource, error) {
+	return t.client.Get(ctx, name, metav1.GetOptions{})
+}
+
+func (t typedClient[T, L]) create(ctx context.Context, obj resource) error {
+	typed, ok := obj.(T)
+	if !ok {
+		return fmt.Errorf("unexpected object type %T", obj)
+	}
+
+	_, err := t.client.Create(ctx, typed, metav1.CreateOptions{})
+	return err
+}
+
+func (t typedClient[T, L]) update(ctx context.Context, obj resource, dryRun bool) error {
+	typed, ok := obj.(T)
+	if !ok {
+		return fmt.Errorf("unexpected object type %T", obj)
+	}
+
+	options := metav1.UpdateOptions{}
+	if dryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	_, err := t.client.Update(ctx, typed, options)
+	return err
+}
+
+func (t typedClient[T, L]) delete(ctx context.Context, name string) error {
+	propagation := metav1.DeletePropagationForeground
+	return t.client.Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+}
+
+func (t typedClient[T, L]) list(ctx context.Context, selector string) ([]resource, error) {
+	list, err := t.client.List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := []resource{}
+	for _, item := range items {
+		r, ok := item.(resource)
+		if !ok {
+			return nil, fmt.Errorf("unexpected list item type %T", item)
+		}
+		resources = append(resources, r)
+	}
+
+	return resources, nil
+}
+
+// returns the client for a kind of resource in a namespace
+func clientFor(clientset kubernetes.Interface, kind string, namespace string) (resourceClient, error) {
+	switch kind {
+	case namespaceKind:
+		return typedClient[*apiv1.Namespace, *apiv1.NamespaceList]{clientset.CoreV1().Namespaces()}, nil
+	case deploymentKind:
+		return typedClient[*appsv1.Deployment, *appsv1.DeploymentList]{clientset.AppsV1().Deployments(namespace)}, nil
+	case daemonsetKind:
+		return typedClient[*appsv1.DaemonSet, *appsv1.DaemonSetList]{clientset.AppsV1().DaemonSets(namespace)}, nil
+	case statefulsetKind:
+		return typedClient[*appsv1.StatefulSet, *appsv1.StatefulSetList]{clientset.AppsV1().StatefulSets(namespace)}, nil
+	case jobKind:
+		return typedClient[*batchv1.Job, *batchv1.JobList]{clientset.BatchV1().Jobs(namespace)}, nil
+	case cronjobKind:
+		return typedClient[*batchv1.CronJob, *batchv1.CronJobList]{clientset.BatchV1().CronJobs(namespace)}, nil
+	case serviceKind:
+		return typedClient[*apiv1.Service, *apiv1.ServiceList]{clientset.CoreV1().Services(namespace)}, nil
+	case configMapKind:
+		return typedClient[*apiv1.ConfigMap, *apiv1.ConfigMapList]{clientset.CoreV1().ConfigMaps(namespace)}, nil
+	case secretKind:
+		return typedClient[*apiv1.Secret, *apiv1.SecretList]{clientset.CoreV1().Secrets(namespace)}, nil
+	case ingressKind:
+		return typedClient[*networkingv1.Ingress, *networkingv1.IngressList]{clientset.NetworkingV1().Ingresses(namespace)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported resource kind %s", kind)
+	}
+}
+
+// deletes a resource and waits until it is gone from the cluster
+func deleteAndWait(ctx context.Context, client resourceClient, name string) error {
+	err := client.delete(ctx, name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return wait.PollUntilContextTimeout(ctx, deletionPollInterval, deletionTimeout, true, func(ctx context.Context) (bool, error) {
+		_, err := client.get(ctx, name)
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+// updates a live object in place
+func updateResource(ctx context.Context, client resourceClient, desired resource, live resource) error {
+	desired.SetResourceVersion(live.GetResourceVersion())
+	err := client.update(ctx, desired, false)
+	if err != nil {
+		return fmt.Errorf("error updating %s: %w", desired.GetName(), err)
+	}
+
+	return nil
+}
+
+// checks with the api server whether a live object can be updated in place, without changing it
+func checkUpdate(ctx context.Context, client resourceClient, desired resource, live resource) error {
+	check := desired.DeepCopyObject().(resource)
+	check.SetResourceVersion(live.GetResourceVersion())
+	return client.update(ctx, check, true)
+}
+
+// deletes an object and creates it again, for changes to fields that cannot be updated
+func replaceResource(ctx context.Context, client resourceClient, desired resource) error {
+	err := deleteAndWait(ctx, client, desired.GetName())
+	if err != nil {
+		return fmt.Errorf("error deleting %s: %w", desired.GetName(), err)
+	}
+
+	desired.SetResourceVersion("")
+	err = client.create(ctx, desired)
+	if err != nil {
+		return fmt.Errorf("error recreating %s: %w", desired.GetName(), err)
+	}
+
+	return nil
+}
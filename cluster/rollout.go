@@ -0,0 +1,303 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	DefaultTimeout = 5 * time.Minute
+
+	rolloutPollInterval = 2 * time.Second
+	rolloutEvents       = 10
+
+	// workload kinds that are rolled out rather than run to completion
+	rolloutKinds = map[string]bool{
+		deploymentKind:  true,
+		daemonsetKind:   true,
+		statefulsetKind: true,
+	}
+
+	errProgressDeadlineExceeded = errors.New("progress deadline exceeded")
+)
+
+// the time to wait for workloads to roll out and jobs to complete
+func (c *Cluster) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return DefaultTimeout
+}
+
+// waits for the workloads of a plan to roll out
+func waitForRollouts(ctx context.Context, clientset *kubernetes.Clientset, plan *Plan, all bool, timeout time.Duration) error {
+	for _, change := range rolloutChanges(plan, all) {
+		err := waitForRollout(ctx, clientset, change, timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// returns the workloads a plan changes, or all kept ones if all is set
+func rolloutChanges(plan *Plan, all bool) []Change {
+	changes := []Change{}
+	for _, change := range plan.Resources {
+		if !rolloutKinds[change.Kind] {
+			continue
+		}
+
+		switch change.Action {
+		case createAction, updateAction, replaceAction:
+			changes = append(changes, change)
+		case unchangedAction:
+			if all {
+				changes = append(changes, change)
+			}
+		}
+	}
+
+	return changes
+}
+
+// checks if a creation is resumed from a checkpoint
+func (c *Cluster) resuming(cp *checkpoint) bool {
+	return c.Resume && cp != nil
+}
+
+// waits for a workload to roll out, printing the state of its pods if it does not
+func waitForRollout(ctx context.Context, clientset *kubernetes.Clientset, change Change, timeout time.Duration) error {
+	slog.Info("Waiting for rollout", "kind", change.Kind, "namespace", change.Namespace, "name", change.Name)
+
+	var progress string
+	err := wait.PollUntilContextTimeout(ctx, rolloutPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		var done bool
+		var err error
+		done, progress, err = rolloutStatus(ctx, clientset, change)
+		return done, err
+	})
+	if err != nil {
+		if progress == "" {
+			progress = err.Error()
+		}
+
+		slog.Error("Rollout failed", "kind", change.Kind, "namespace", change.Namespace, "name", change.Name, "reason", progress)
+		podTemplate := podTemplateOf(change.Object)
+		if podTemplate != nil {
+			fmt.Fprint(os.Stderr, rolloutReport(ctx, clientset, change.Namespace, change.Kind, change.Name, podTemplate.Labels))
+		}
+		if errors.Is(err, errProgressDeadlineExceeded) {
+			return fmt.Errorf("%s %s/%s exceeded its progress deadline: %s", change.Kind, change.Namespace, change.Name, progress)
+		}
+		return fmt.Errorf("%s %s/%s did not roll out within %s: %s", change.Kind, change.Namespace, change.Name, timeout, progress)
+	}
+
+	slog.Info("Rollout complete", "kind", change.Kind, "namespace", change.Namespace, "name", change.Name)
+	return nil
+}
+
+// checks if a workload has rolled out, describing its progress if not
+func rolloutStatus(ctx context.Context, clientset *kubernetes.Clientset, change Change) (bool, string, error) {
+	switch change.Kind {
+	case deploymentKind:
+		deployment, err := clientset.AppsV1().Deployments(change.Namespace).Get(ctx, change.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, "", err
+		}
+		return deploymentRolledOut(deployment)
+	case daemonsetKind:
+		daemonset, err := clientset.AppsV1().DaemonSets(change.Namespace).Get(ctx, change.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, "", err
+		}
+		done, progress := daemonsetRolledOut(daemonset)
+		return done, progress, nil
+	case statefulsetKind:
+		statefulset, err := clientset.AppsV1().StatefulSets(change.Namespace).Get(ctx, change.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, "", err
+		}
+		done, progress := statefulsetRolledOut(statefulset)
+		return done, progress, nil
+	default:
+		return true, "", nil
+	}
+}
+
+func deploymentRolledOut(deployment *appsv1.Deployment) (bool, string, error) {
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false, "waiting for the deployment spec to be observed", nil
+	}
+
+	// the deployment controller has given up
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return false, condition.Message, errProgressDeadlineExceeded
+		}
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	status := deployment.Status
+	switch {
+	case status.UpdatedReplicas < replicas:
+		return false, fmt.Sprintf("%d of %d replicas updated", status.UpdatedReplicas, replicas), nil
+	case status.Replicas > status.UpdatedReplicas:
+		return false, fmt.Sprintf("%d old replicas pending termination", status.Replicas-status.UpdatedReplicas), nil
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		return false, fmt.Sprintf("%d of %d updated replicas available", status.AvailableReplicas, status.UpdatedReplicas), nil
+	}
+
+	return true, "", nil
+}
+
+func daemonsetRolledOut(daemonset *appsv1.DaemonSet) (bool, string) {
+	if daemonset.Status.ObservedGeneration < daemonset.Generation {
+		return false, "waiting for the daemonset spec to be observed"
+	}
+
+	status := daemonset.Status
+	switch {
+	case status.UpdatedNumberScheduled < status.DesiredNumberScheduled:
+		return false, fmt.Sprintf("%d of %d pods updated", status.UpdatedNumberScheduled, status.DesiredNumberScheduled)
+	case status.NumberAvailable < status.DesiredNumberScheduled:
+		return false, fmt.Sprintf("%d of %d updated pods available", status.NumberAvailable, status.DesiredNumberScheduled)
+	}
+
+	return true, ""
+}
+
+func statefulsetRolledOut(statefulset *appsv1.StatefulSet) (bool, string) {
+	if statefulset.Status.ObservedGeneration < statefulset.Generation {
+		return false, "waiting for the statefulset spec to be observed"
+	}
+
+	replicas := int32(1)
+	if statefulset.Spec.Replicas != nil {
+		replicas = *statefulset.Spec.Replicas
+	}
+
+	status := statefulset.Status
+	switch {
+	case status.ReadyReplicas < replicas:
+		return false, fmt.Sprintf("%d of %d replicas ready", status.ReadyReplicas, replicas)
+	case status.UpdateRevision != status.CurrentRevision:
+		return false, fmt.Sprintf("%d of %d replicas updated", status.UpdatedReplicas, replicas)
+	}
+
+	return true, ""
+}
+
+// describes the states, events and logs of a workload's pods that are not ready
+func rolloutReport(ctx context.Context, clientset *kubernetes.Clientset, namespace string, kind string, name string, podLabels map[string]string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== %s %s/%s ===\n", kind, namespace, name)
+	writeEvents(ctx, &b, clientset, namespace, name)
+
+	selector := labels.SelectorFromSet(podLabels).String()
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		fmt.Fprintf(&b, "could not list pods: %v\n", err)
+		return b.String()
+	}
+
+	for _, pod := range pods.Items {
+		if podReady(pod) {
+			continue
+		}
+
+		fmt.Fprintf(&b, "--- pod %s (%s) ---\n", pod.Name, pod.Status.Phase)
+		for _, condition := range pod.Status.Conditions {
+			if condition.Status != apiv1.ConditionTrue && condition.Message != "" {
+				fmt.Fprintf(&b, "%s: %s\n", condition.Type, condition.Message)
+			}
+		}
+
+		for _, container := range pod.Status.ContainerStatuses {
+			fmt.Fprintf(&b, "container %s: %s (restarts: %d)\n", container.Name, containerState(container.State), container.RestartCount)
+			if container.LastTerminationState.Terminated != nil {
+				fmt.Fprintf(&b, "  last state: %s\n", containerState(container.LastTerminationState))
+			}
+		}
+
+		writeEvents(ctx, &b, clientset, namespace, pod.Name)
+		b.WriteString(podLogs(ctx, clientset, pod))
+	}
+
+	return b.String()
+}
+
+func podReady(pod apiv1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == apiv1.PodReady {
+			return condition.Status == apiv1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func containerState(state apiv1.ContainerState) string {
+	switch {
+	case state.Waiting != nil:
+		return strings.TrimSpace(fmt.Sprintf("waiting: %s %s", state.Waiting.Reason, state.Waiting.Message))
+	case state.Terminated != nil:
+		return strings.TrimSpace(fmt.Sprintf("terminated: %s (exit code %d) %s", state.Terminated.Reason, state.Terminated.ExitCode, state.Terminated.Message))
+	case state.Running != nil:
+		return "running"
+	default:
+		return "unknown"
+	}
+}
+
+// writes the most recent events of an object in a namespace
+func writeEvents(ctx context.Context, w io.Writer, clientset *kubernetes.Clientset, namespace string, name string) {
+	selector := fields.OneTermEqualSelector("involvedObject.name", name).String()
+	events, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		fmt.Fprintf(w, "could not list events of %s: %v\n", name, err)
+		return
+	}
+
+	items := events.Items
+	sort.Slice(items, func(i, j int) bool {
+		return eventTime(items[i]).Before(eventTime(items[j]))
+	})
+	if len(items) > rolloutEvents {
+		items = items[len(items)-rolloutEvents:]
+	}
+
+	for _, event := range items {
+		fmt.Fprintf(w, "event %s %s: %s\n", event.Type, event.Reason, strings.TrimSpace(event.Message))
+	}
+}
+
+func eventTime(event apiv1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
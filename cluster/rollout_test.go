@@ -0,0 +1,35 @@
+package cluster
+
+import (
+	"errors"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+)
+
+func TestDeploymentRolledOutProgressDeadlineExceeded(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		Status: appsv1.DeploymentStatus{
+			Conditions: []appsv1.DeploymentCondition{{
+				Type:    appsv1.DeploymentProgressing,
+				Status:  apiv1.ConditionFalse,
+				Reason:  "ProgressDeadlineExceeded",
+				Message: `ReplicaSet "web-5d8f" has timed out progressing.`,
+			}},
+		},
+	}
+
+	done, progress, err := deploymentRolledOut(deployment)
+	if done || !errors.Is(err, errProgressDeadlineExceeded) {
+		t.Fatalf("expected an exceeded progress deadline to stop the rollout, got done %v and error %v", done, err)
+	}
+	if progress != deployment.Status.Conditions[0].Message {
+		t.Fatalf("expected the condition message as progress, got %q", progress)
+	}
+
+	deployment.Status.Conditions = nil
+	if _, _, err := deploymentRolledOut(deployment); err != nil {
+		t.Fatalf("expected a progressing deployment to keep being polled, got %v", err)
+	}
+}
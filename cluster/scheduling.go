@@ -0,0 +1,185 @@
+package cluster
+
+import (
+	"fmt"
+	"runtime"
+	"skillet/skillet-kind/charts"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	preferredAntiAffinity = "preferred"
+	requiredAntiAffinity  = "required"
+
+	hostnameLabel     = "kubernetes.io/hostname"
+	osLabel           = "kubernetes.io/os"
+	archLabel         = "kubernetes.io/arch"
+	controlPlaneLabel = "node-role.kubernetes.io/control-plane"
+)
+
+// a toleration letting an application's pods run on nodes with a matching taint
+type Toleration struct {
+	Key      string `yaml:"key"`
+	Operator string `yaml:"operator"`
+	Value    string `yaml:"value"`
+	Effect   string `yaml:"effect"`
+}
+
+func (a *Application) tolerations() []apiv1.Toleration {
+	tolerations := []apiv1.Toleration{}
+	for _, toleration := range a.Tolerations {
+		tolerations = append(tolerations, apiv1.Toleration{
+			Key:      toleration.Key,
+			Operator: apiv1.TolerationOperator(toleration.Operator),
+			Value:    toleration.Value,
+			Effect:   apiv1.TaintEffect(toleration.Effect),
+		})
+	}
+
+	return tolerations
+}
+
+// keeps the application's pods on separate nodes
+func (a *Application) affinity() *apiv1.Affinity {
+	term := apiv1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: a.selectorLabels(),
+		},
+		TopologyKey: hostnameLabel,
+	}
+
+	switch a.AntiAffinity {
+	case preferredAntiAffinity:
+		return &apiv1.Affinity{
+			PodAntiAffinity: &apiv1.PodAntiAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []apiv1.WeightedPodAffinityTerm{
+					{Weight: 100, PodAffinityTerm: term},
+				},
+			},
+		}
+	case requiredAntiAffinity:
+		return &apiv1.Affinity{
+			PodAntiAffinity: &apiv1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []apiv1.PodAffinityTerm{term},
+			},
+		}
+	default:
+		return nil
+	}
+}
+
+// spreads the application's pods evenly over the nodes
+func (a *Application) topologySpreadConstraints() []apiv1.TopologySpreadConstraint {
+	if !a.Spread {
+		return nil
+	}
+
+	return []apiv1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       hostnameLabel,
+			WhenUnsatisfiable: apiv1.ScheduleAnyway,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: a.selectorLabels(),
+			},
+		},
+	}
+}
+
+// the labels and taints each node of the cluster will have once kind creates it
+func (c *ClusterConfig) nodeLabelsAndTaints(clusterName string) ([]map[string]string, [][]Taint) {
+	nodes := c.Nodes.nodes()
+	workers := 0
+	for _, node := range nodes {
+		if node.Role == workerRole {
+			workers++
+		}
+	}
+
+	allLabels := []map[string]string{}
+	allTaints := [][]Taint{}
+	counts := map[string]int{}
+	ingressNode := false
+	for _, node := range nodes {
+		// kind names nodes after their role, numbering all but the first of each role
+		counts[node.Role]++
+		hostname := clusterName + "-" + node.Role
+		if counts[node.Role] > 1 {
+			hostname += fmt.Sprint(counts[node.Role])
+		}
+
+		labels := map[string]string{
+			hostnameLabel: hostname,
+			osLabel:       "linux",
+			archLabel:     runtime.GOARCH,
+		}
+		for key, value := range node.Labels {
+			labels[key] = value
+		}
+
+		taints := node.Taints
+		if node.Role == controlPlaneRole {
+			labels[controlPlaneLabel] = ""
+
+			// kind only leaves the control plane tainted when there are workers to schedule on
+			if len(taints) == 0 && workers > 0 {
+				taints = []Taint{{Key: controlPlaneLabel, Effect: string(apiv1.TaintEffectNoSchedule)}}
+			}
+
+			if c.Ingress.enabled() && !ingressNode {
+				labels[charts.IngressReadyLabel] = "true"
+				ingressNode = true
+			}
+		}
+
+		allLabels = append(allLabels, labels)
+		allTaints = append(allTaints, taints)
+	}
+
+	return allLabels, allTaints
+}
+
+// checks if tolerations tolerate a node's taints
+func tolerates(tolerations []Toleration, taints []Taint) bool {
+	for _, taint := range taints {
+		if taint.Effect == string(apiv1.TaintEffectPreferNoSchedule) {
+			continue
+		}
+
+		tolerated := false
+		for _, toleration := range tolerations {
+			if toleration.toleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (t *Toleration) toleratesTaint(taint Taint) bool {
+	if t.Effect != "" && t.Effect != taint.Effect {
+		return false
+	}
+
+	if t.Key == "" {
+		return t.Operator == string(apiv1.TolerationOpExists)
+	} else if t.Key != taint.Key {
+		return false
+	}
+
+	switch apiv1.TolerationOperator(t.Operator) {
+	case apiv1.TolerationOpExists:
+		return true
+	case "", apiv1.TolerationOpEqual:
+		return t.Value == taint.Value
+	default:
+		return false
+	}
+}
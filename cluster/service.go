@@ -0,0 +1,177 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var (
+	minNodePort = 30000
+	maxNodePort = 32767
+
+	externalServiceSuffix = "-external"
+
+	// service types ordered from least to most exposed
+	serviceTypes = []apiv1.ServiceType{apiv1.ServiceTypeClusterIP, apiv1.ServiceTypeNodePort, apiv1.ServiceTypeLoadBalancer}
+)
+
+// a port exposed by an application
+type Port struct {
+	Name          string `yaml:"name"`
+	ContainerPort int    `yaml:"container_port"`
+	Protocol      string `yaml:"protocol"`
+	ServiceType   string `yaml:"service_type"`
+	HostPort      int    `yaml:"host_port"`
+	NodePort      int    `yaml:"node_port"`
+}
+
+// builds the service exposing an application's ports, or nil if it needs none
+func (a *Application) service() *apiv1.Service {
+	if len(a.Ports) == 0 {
+		return nil
+	}
+
+	serviceType := a.serviceType()
+	name := a.Name
+	if a.Type == statefulsetType {
+		if serviceType == apiv1.ServiceTypeClusterIP {
+			return nil
+		}
+		name += externalServiceSuffix
+	}
+
+	return &apiv1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       serviceKind,
+			APIVersion: apiv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: a.objectMeta(name),
+		Spec: apiv1.ServiceSpec{
+			Type:     serviceType,
+			Selector: a.selectorLabels(),
+			Ports:    a.servicePorts(serviceType),
+		},
+	}
+}
+
+// the most exposed service type requested by any of the application's ports
+func (a *Application) serviceType() apiv1.ServiceType {
+	rank := 0
+	for _, port := range a.Ports {
+		portType := apiv1.ServiceType(port.ServiceType)
+		if port.HostPort != 0 && portType != apiv1.ServiceTypeLoadBalancer {
+			portType = apiv1.ServiceTypeNodePort
+		}
+
+		for i, serviceType := range serviceTypes {
+			if serviceType == portType && i > rank {
+				rank = i
+			}
+		}
+	}
+
+	return serviceTypes[rank]
+}
+
+func (a *Application) servicePorts(serviceType apiv1.ServiceType) []apiv1.ServicePort {
+	ports := []apiv1.ServicePort{}
+	for _, port := range a.Ports {
+		servicePort := apiv1.ServicePort{
+			Name:       port.name(),
+			Protocol:   port.protocol(),
+			Port:       int32(port.ContainerPort),
+			TargetPort: intstr.FromInt32(int32(port.ContainerPort)),
+		}
+		if serviceType != apiv1.ServiceTypeClusterIP {
+			servicePort.NodePort = int32(port.nodePort())
+		}
+		ports = append(ports, servicePort)
+	}
+
+	return ports
+}
+
+// copies node ports the api server allocated onto the desired service
+func keepAllocatedNodePorts(desired resource, live resource) {
+	desiredService, ok := desired.(*apiv1.Service)
+	if !ok || desiredService.Spec.Type == apiv1.ServiceTypeClusterIP {
+		return
+	}
+	liveService, ok := live.(*apiv1.Service)
+	if !ok {
+		return
+	}
+
+	for i, port := range desiredService.Spec.Ports {
+		if port.NodePort != 0 {
+			continue
+		}
+
+		for _, livePort := range liveService.Spec.Ports {
+			if livePort.Port == port.Port && livePort.Protocol == port.Protocol {
+				desiredService.Spec.Ports[i].NodePort = livePort.NodePort
+			}
+		}
+	}
+}
+
+func (a *Application) containerPorts() []apiv1.ContainerPort {
+	ports := []apiv1.ContainerPort{}
+	for _, port := range a.Ports {
+		ports = append(ports, apiv1.ContainerPort{
+			Name:          port.name(),
+			ContainerPort: int32(port.ContainerPort),
+			Protocol:      port.protocol(),
+		})
+	}
+
+	return ports
+}
+
+// returns the port mappings forwarding host ports to node ports
+func hostPortMappings(apps []Application) []PortMapping {
+	mappings := []PortMapping{}
+	for _, app := range apps {
+		for _, port := range app.Ports {
+			if port.HostPort == 0 {
+				continue
+			}
+
+			mappings = append(mappings, PortMapping{
+				ContainerPort: port.nodePort(),
+				HostPort:      port.HostPort,
+				Protocol:      string(port.protocol()),
+			})
+		}
+	}
+
+	return mappings
+}
+
+func (p *Port) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return fmt.Sprintf("%s-%d", strings.ToLower(string(p.protocol())), p.ContainerPort)
+}
+
+func (p *Port) protocol() apiv1.Protocol {
+	if p.Protocol == "" {
+		return apiv1.ProtocolTCP
+	}
+	return apiv1.Protocol(p.Protocol)
+}
+
+// the node port of a port, defaulting to its host port
+func (p *Port) nodePort() int {
+	if p.NodePort != 0 {
+		return p.NodePort
+	} else if p.HostPort >= minNodePort && p.HostPort <= maxNodePort {
+		return p.HostPort
+	}
+	return 0
+}
@@ -0,0 +1,376 @@
+package cluster
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	caCertFile = "ca.crt"
+	caKeyFile  = "ca.key"
+	certsDir   = "certs"
+
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 825 * 24 * time.Hour
+	renewBefore  = 30 * 24 * time.Hour
+
+	tlsSecretSuffix = "-tls"
+)
+
+// local certificate authority for a cluster's ingress hosts
+type certificateAuthority struct {
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// loads the cluster's certificate authority, or nil if it does not exist yet
+func (c *Cluster) loadCertificateAuthority() (*certificateAuthority, error) {
+	dir, err := c.stateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	certPEM, keyPEM, err := readKeyPair(filepath.Join(dir, caCertFile), filepath.Join(dir, caKeyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading certificate authority: %w", err)
+	}
+
+	ca := &certificateAuthority{dir: dir}
+	ca.cert, ca.key, err = parseKeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing certificate authority: %w", err)
+	}
+	return ca, nil
+}
+
+// loads the cluster's certificate authority, creating it on first use
+func (c *Cluster) certificateAuthority() (*certificateAuthority, error) {
+	ca, err := c.loadCertificateAuthority()
+	if err != nil || ca != nil {
+		return ca, err
+	}
+
+	dir, err := c.stateDir()
+	if err != nil {
+		return nil, err
+	}
+	ca = &certificateAuthority{dir: dir}
+
+	slog.Info("Creating local certificate authority", "cluster", c.Name, "directory", dir)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generating certificate authority key: %w", err)
+	}
+
+	template, err := certificateTemplate(caValidity)
+	if err != nil {
+		return nil, err
+	}
+	template.Subject = pkix.Name{Organization: []string{"skillet"}, CommonName: "skillet " + c.Name + " local CA"}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.MaxPathLenZero = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating certificate authority: %w", err)
+	}
+
+	certPEM, keyPEM, err := encodeKeyPair(der, key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeKeyPair(filepath.Join(dir, caCertFile), filepath.Join(dir, caKeyFile), certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("error saving certificate authority: %w", err)
+	}
+
+	ca.cert, ca.key, err = parseKeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return ca, nil
+}
+
+// returns the saved certificate and key for a host, or nil if it is missing or expiring
+func (ca *certificateAuthority) savedCertificate(host string) ([]byte, []byte, error) {
+	certPEM, keyPEM, err := readKeyPair(ca.certificatePaths(host))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("error reading certificate for %s: %w", host, err)
+	}
+
+	cert, _, err := parseKeyPair(certPEM, keyPEM)
+	if err != nil || !ca.issued(cert, host) {
+		return nil, nil, nil
+	}
+	return certPEM, keyPEM, nil
+}
+
+func (ca *certificateAuthority) certificatePaths(host string) (string, string) {
+	return filepath.Join(ca.dir, certsDir, host+".crt"), filepath.Join(ca.dir, certsDir, host+".key")
+}
+
+// returns the certificate and key for a host, issuing one if needed
+func (ca *certificateAuthority) certificate(host string) ([]byte, []byte, error) {
+	certPEM, keyPEM, err := ca.savedCertificate(host)
+	if err != nil || certPEM != nil {
+		return certPEM, keyPEM, err
+	}
+
+	slog.Info("Issuing certificate", "host", host)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error generating key for %s: %w", host, err)
+	}
+
+	template, err := certificateTemplate(certValidity)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.Subject = pkix.Name{Organization: []string{"skillet"}, CommonName: host}
+	template.DNSNames = []string{host}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error issuing certificate for %s: %w", host, err)
+	}
+
+	certPEM, keyPEM, err = encodeKeyPair(der, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPath, keyPath := ca.certificatePaths(host)
+	err = writeKeyPair(certPath, keyPath, certPEM, keyPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error saving certificate for %s: %w", host, err)
+	}
+
+	return certPEM, keyPEM, nil
+}
+
+// checks a saved certificate is valid for the host
+func (ca *certificateAuthority) issued(cert *x509.Certificate, host string) bool {
+	if cert.CheckSignatureFrom(ca.cert) != nil || cert.VerifyHostname(host) != nil {
+		return false
+	}
+	return time.Now().Add(renewBefore).Before(cert.NotAfter)
+}
+
+// plans the tls secret of an ingress host without writing any certificate
+func (c *Cluster) planTLSSecret(ctx context.Context, clientset *kubernetes.Clientset, app *Application) (Change, error) {
+	host := app.Ingress.Host
+
+	var certPEM, keyPEM []byte
+	ca, err := c.loadCertificateAuthority()
+	if err == nil && ca != nil {
+		certPEM, keyPEM, err = ca.savedCertificate(host)
+	}
+	if err != nil {
+		return Change{}, err
+	}
+
+	if certPEM != nil {
+		return planChange(ctx, clientset, app.tlsSecret(certPEM, keyPEM))
+	}
+
+	change, err := planChange(ctx, clientset, app.tlsSecret(nil, nil))
+	if err != nil {
+		return change, err
+	}
+
+	if change.live != nil {
+		change.Action = updateAction
+	}
+	change.Diff = fmt.Sprintf("certificate for %s is issued when the plan is applied\n", host)
+	change.issueHost = host
+	return change, nil
+}
+
+// issues the certificate of a planned tls secret
+func (c *Cluster) issueCertificate(change Change) error {
+	ca, err := c.certificateAuthority()
+	if err != nil {
+		return err
+	}
+
+	certPEM, keyPEM, err := ca.certificate(change.issueHost)
+	if err != nil {
+		return err
+	}
+
+	secret := change.Object.(*apiv1.Secret)
+	secret.Data = map[string][]byte{
+		apiv1.TLSCertKey:       certPEM,
+		apiv1.TLSPrivateKeyKey: keyPEM,
+	}
+	return nil
+}
+
+// builds the tls secret an application's ingress serves its host with
+func (a *Application) tlsSecret(certPEM []byte, keyPEM []byte) *apiv1.Secret {
+	return &apiv1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       secretKind,
+			APIVersion: apiv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: a.objectMeta(a.Name + tlsSecretSuffix),
+		Type:       apiv1.SecretTypeTLS,
+		Data: map[string][]byte{
+			apiv1.TLSCertKey:       certPEM,
+			apiv1.TLSPrivateKeyKey: keyPEM,
+		},
+	}
+}
+
+// returns the PEM encoded certificate of the cluster's local certificate authority
+func (c *Cluster) CABundle() ([]byte, error) {
+	if _, err := c.resolveName(); err != nil {
+		return nil, err
+	}
+
+	dir, err := c.stateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	bundle, err := os.ReadFile(filepath.Join(dir, caCertFile))
+	if errors.Is(err, os.ErrNotExist) {
+		err = status.Errorf(codes.NotFound, "cluster %s has no certificate authority. One is created when a cluster with ingress hosts is created or applied", c.Name)
+		slog.Error(err.Error())
+		return nil, err
+	} else if err != nil {
+		err = fmt.Errorf("error reading certificate authority: %w", err)
+		slog.Error(err.Error())
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// returns a copy of a secret with its values replaced by digests
+func redact(obj resource) resource {
+	secret, ok := obj.(*apiv1.Secret)
+	if !ok {
+		return obj
+	}
+
+	redacted := secret.DeepCopy()
+	redacted.Data = nil
+	redacted.StringData = map[string]string{}
+	for key, value := range secret.Data {
+		redacted.StringData[key] = redactedValue(value)
+	}
+	for key, value := range secret.StringData {
+		redacted.StringData[key] = redactedValue([]byte(value))
+	}
+
+	return redacted
+}
+
+func redactedValue(value []byte) string {
+	sum := sha256.Sum256(value)
+	return fmt.Sprintf("<redacted sha256:%x>", sum[:8])
+}
+
+func certificateTemplate(validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("error generating serial number: %w", err)
+	}
+
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(validity),
+	}, nil
+}
+
+func encodeKeyPair(der []byte, key *ecdsa.PrivateKey) ([]byte, []byte, error) {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error encoding private key: %w", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+func parseKeyPair(certPEM []byte, keyPEM []byte) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, nil, fmt.Errorf("invalid PEM data")
+	}
+
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cert, key, nil
+}
+
+func readKeyPair(certPath string, keyPath string) ([]byte, []byte, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return certPEM, keyPEM, nil
+}
+
+func writeKeyPair(certPath string, keyPath string, certPEM []byte, keyPEM []byte) error {
+	err := os.MkdirAll(filepath.Dir(certPath), 0o700)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(keyPath, keyPEM, 0o600)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(certPath, certPEM, 0o644)
+}
@@ -0,0 +1,49 @@
+package cluster
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+func TestPlanTLSSecretWritesNothing(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	c := &Cluster{Name: "test"}
+	app := Application{Name: "web", Namespace: "default", Ingress: &ApplicationIngress{Host: "web.localhost"}}
+
+	change, err := c.planTLSSecret(context.Background(), nil, &app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Action != createAction || change.issueHost != app.Ingress.Host {
+		t.Fatalf("expected the certificate to be issued on apply, got action %s", change.Action)
+	}
+
+	entries, err := os.ReadDir(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) > 0 {
+		t.Fatalf("planning wrote %s to the home directory", entries[0].Name())
+	}
+
+	err = c.issueCertificate(change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(change.Object.(*apiv1.Secret).Data[apiv1.TLSCertKey]) == 0 {
+		t.Fatalf("secret has no certificate after it was issued")
+	}
+
+	change, err = c.planTLSSecret(context.Background(), nil, &app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.issueHost != "" {
+		t.Fatalf("expected the saved certificate to be reused")
+	}
+}
@@ -4,12 +4,20 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"unicode"
 
 	"github.com/docker/docker/client"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	apiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 func (c *Cluster) Validate(ctx context.Context) error {
@@ -32,16 +40,32 @@ func (c *Cluster) Validate(ctx context.Context) error {
 		return err
 	}
 
-	// validate control plane nodes is at least 1
-	if config.Nodes.ControlPlane < 1 {
-		err = status.Errorf(codes.FailedPrecondition, "the number of control plane nodes must be greater than 0")
+	if err := validateNetworking(config.Networking); err != nil {
+		err = fmt.Errorf("cluster has invalid networking: %w", err)
 		slog.Error(err.Error())
 		return err
 	}
 
-	// validate worker nodes is not negative
-	if config.Nodes.Worker < 0 {
-		err = status.Errorf(codes.FailedPrecondition, "the number of worker nodes cannot be negative")
+	if err := validateRegistry(config); err != nil {
+		err = fmt.Errorf("cluster has an invalid registry: %w", err)
+		slog.Error(err.Error())
+		return err
+	}
+
+	if err := c.validateCharts(config); err != nil {
+		err = fmt.Errorf("cluster has invalid charts: %w", err)
+		slog.Error(err.Error())
+		return err
+	}
+
+	if err := validatePatches(config); err != nil {
+		err = fmt.Errorf("cluster has invalid kind settings: %w", err)
+		slog.Error(err.Error())
+		return err
+	}
+
+	if err := validateNodes(config, filepath.Dir(c.ConfigFile)); err != nil {
+		err = fmt.Errorf("cluster has invalid nodes: %w", err)
 		slog.Error(err.Error())
 		return err
 	}
@@ -73,30 +97,102 @@ func (c *Cluster) Validate(ctx context.Context) error {
 		}
 
 		switch app.Type {
-		case "daemonset":
+		case daemonsetType:
 			if app.Replicas > 0 {
 				err = status.Errorf(codes.FailedPrecondition, "replicas cannot be specified for daemonsets")
 				slog.Error(err.Error())
 				return err
 			}
-		case "deployment":
+		case deploymentType, statefulsetType:
 			if app.Replicas < 1 {
 				err = status.Errorf(codes.FailedPrecondition, "application replicas must be at least 1")
 				slog.Error(err.Error())
 				return err
 			}
+		case jobType, cronjobType:
+			if app.Replicas > 0 {
+				err = status.Errorf(codes.FailedPrecondition, "replicas cannot be specified for jobs and cronjobs")
+				slog.Error(err.Error())
+				return err
+			}
 		default:
-			err = status.Errorf(codes.FailedPrecondition, "application type must be one of [deployment, daemonset]")
+			err = status.Errorf(codes.FailedPrecondition, "application type must be one of [deployment, daemonset, statefulset, job, cronjob]")
+			slog.Error(err.Error())
+			return err
+		}
+
+		if err := validateJob(app); err != nil {
+			err = fmt.Errorf("application %s has invalid job settings: %w", app.Name, err)
+			slog.Error(err.Error())
+			return err
+		}
+
+		if err := validatePorts(app); err != nil {
+			err = fmt.Errorf("application %s has invalid ports: %w", app.Name, err)
+			slog.Error(err.Error())
+			return err
+		}
+
+		if err := validateResources(app); err != nil {
+			err = fmt.Errorf("application %s has invalid resources: %w", app.Name, err)
+			slog.Error(err.Error())
+			return err
+		}
+
+		if err := validateProbes(app); err != nil {
+			err = fmt.Errorf("application %s has invalid probes: %w", app.Name, err)
+			slog.Error(err.Error())
+			return err
+		}
+
+		if err := validateScheduling(app, config); err != nil {
+			err = fmt.Errorf("application %s has invalid scheduling: %w", app.Name, err)
+			slog.Error(err.Error())
+			return err
+		}
+
+		if err := validateConfigData(app); err != nil {
+			err = fmt.Errorf("application %s has invalid configuration data: %w", app.Name, err)
+			slog.Error(err.Error())
+			return err
+		}
+
+		if err := validateIngress(app, config.Ingress); err != nil {
+			err = fmt.Errorf("application %s has invalid ingress: %w", app.Name, err)
+			slog.Error(err.Error())
+			return err
+		}
+
+		if err := validateDependencies(app, config.Applications); err != nil {
+			err = fmt.Errorf("application %s has invalid dependencies: %w", app.Name, err)
+			slog.Error(err.Error())
+			return err
+		}
+
+		if err := validateStorage(app); err != nil {
+			err = fmt.Errorf("application %s has invalid storage: %w", app.Name, err)
+			slog.Error(err.Error())
+			return err
+		}
+
+		if err := validateBuild(app); err != nil {
+			err = fmt.Errorf("application %s has an invalid build: %w", app.Name, err)
 			slog.Error(err.Error())
 			return err
 		}
 
-		if app.Image == "" {
+		if app.Image == "" && app.Build == nil {
 			err = status.Errorf(codes.FailedPrecondition, "application image must not be empty")
 			slog.Error(err.Error())
 			return err
 		}
 
+		// built images do not exist until the cluster is created, and the nodes pull images
+		// hosted in the local registry themselves
+		if app.Build != nil || config.Registry.hosts(app.Image) {
+			continue
+		}
+
 		if err := imageExists(ctx, app.Image); err != nil {
 			err = fmt.Errorf("error retreiving image %s: %w", app.Image, err)
 			slog.Error(err.Error())
@@ -104,11 +200,619 @@ func (c *Cluster) Validate(ctx context.Context) error {
 		}
 	}
 
+	if err := validateConfigNames(config.Applications); err != nil {
+		err = fmt.Errorf("applications have conflicting config maps or secrets: %w", err)
+		slog.Error(err.Error())
+		return err
+	}
+
+	if err := validateIngressRoutes(config.Applications); err != nil {
+		err = fmt.Errorf("applications have conflicting ingress routes: %w", err)
+		slog.Error(err.Error())
+		return err
+	}
+
+	if err := validateHostPorts(config); err != nil {
+		err = fmt.Errorf("applications have conflicting host ports: %w", err)
+		slog.Error(err.Error())
+		return err
+	}
+
+	if _, err := orderApplications(config.Applications); err != nil {
+		err = status.Errorf(codes.FailedPrecondition, "%v", err)
+		slog.Error(err.Error())
+		return err
+	}
+
 	slog.Info("Cluster configuration is valid")
 	return nil
 }
 
+func validateJob(app Application) error {
+	isJob := app.Type == jobType || app.Type == cronjobType
+	if !isJob && (app.BackoffLimit != nil || app.Completions != nil) {
+		return status.Errorf(codes.FailedPrecondition, "backoff limit and completions can only be specified for jobs and cronjobs")
+	}
+
+	if app.Type != cronjobType && app.Schedule != "" {
+		return status.Errorf(codes.FailedPrecondition, "schedule can only be specified for cronjobs")
+	}
+
+	if app.Type == cronjobType && !validSchedule(app.Schedule) {
+		return status.Errorf(codes.FailedPrecondition, "schedule %q must be a cron expression with 5 fields or a macro such as @hourly", app.Schedule)
+	}
+
+	if app.BackoffLimit != nil && *app.BackoffLimit < 0 {
+		return status.Errorf(codes.FailedPrecondition, "backoff limit cannot be negative")
+	}
+
+	if app.Completions != nil && *app.Completions < 1 {
+		return status.Errorf(codes.FailedPrecondition, "completions must be at least 1")
+	}
+
+	return nil
+}
+
+// checks the shape of a cron schedule. The api server performs the full validation
+func validSchedule(schedule string) bool {
+	if strings.HasPrefix(schedule, "@") {
+		return regexp.MustCompile(`^@(yearly|annually|monthly|weekly|daily|midnight|hourly|every \S+)$`).MatchString(schedule)
+	}
+
+	fields := strings.Fields(schedule)
+	if len(fields) != 5 {
+		return false
+	}
+
+	for _, field := range fields {
+		if !regexp.MustCompile(`^[0-9A-Za-z*?/,-]+$`).MatchString(field) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func validatePorts(app Application) error {
+	if len(app.Ports) > 0 && (app.Type == jobType || app.Type == cronjobType) {
+		return status.Errorf(codes.FailedPrecondition, "ports cannot be specified for jobs and cronjobs")
+	}
+
+	names := map[string]bool{}
+	for _, port := range app.Ports {
+		if port.ContainerPort < 1 || port.ContainerPort > 65535 {
+			return status.Errorf(codes.FailedPrecondition, "container port %d must be between 1 and 65535", port.ContainerPort)
+		}
+
+		if port.HostPort < 0 || port.HostPort > 65535 {
+			return status.Errorf(codes.FailedPrecondition, "host port %d must be between 1 and 65535", port.HostPort)
+		}
+
+		if port.NodePort != 0 && (port.NodePort < minNodePort || port.NodePort > maxNodePort) {
+			return status.Errorf(codes.FailedPrecondition, "node port %d must be between %d and %d", port.NodePort, minNodePort, maxNodePort)
+		}
+
+		if port.HostPort != 0 && port.nodePort() == 0 {
+			return status.Errorf(codes.FailedPrecondition, "host port %d is outside the node port range %d-%d, so a node port must be specified", port.HostPort, minNodePort, maxNodePort)
+		}
+
+		switch apiv1.Protocol(port.Protocol) {
+		case "", apiv1.ProtocolTCP, apiv1.ProtocolUDP, apiv1.ProtocolSCTP:
+		default:
+			return status.Errorf(codes.FailedPrecondition, "port protocol must be one of [TCP, UDP, SCTP]")
+		}
+
+		switch apiv1.ServiceType(port.ServiceType) {
+		case "", apiv1.ServiceTypeClusterIP, apiv1.ServiceTypeNodePort, apiv1.ServiceTypeLoadBalancer:
+		default:
+			return status.Errorf(codes.FailedPrecondition, "service type must be one of [ClusterIP, NodePort, LoadBalancer]")
+		}
+
+		if port.Name != "" {
+			if err := followsNamingConvention(port.Name); err != nil {
+				return fmt.Errorf("port name does not follow naming convention: %w", err)
+			}
+		}
+
+		if names[port.name()] {
+			return status.Errorf(codes.FailedPrecondition, "port name %s is used more than once", port.name())
+		}
+		names[port.name()] = true
+	}
+
+	return nil
+}
+
+// checks that no two ports across applications claim the same host or node port
+func validateHostPorts(config *ClusterConfig) error {
+	hostPorts := map[string]string{}
+	nodePorts := map[string]string{}
+	if config.Networking != nil && config.Networking.APIServerPort != 0 {
+		hostPorts[fmt.Sprintf("%d/%s", config.Networking.APIServerPort, apiv1.ProtocolTCP)] = "the api server"
+	}
+
+	if config.Registry.enabled() {
+		key := fmt.Sprintf("%d/%s", config.Registry.port(), apiv1.ProtocolTCP)
+		if other, ok := hostPorts[key]; ok {
+			return status.Errorf(codes.FailedPrecondition, "host port %s is used by both %s and the registry", key, other)
+		}
+		hostPorts[key] = "the registry"
+	}
+
+	for _, mapping := range config.Ingress.portMappings() {
+		key := fmt.Sprintf("%d/%s", mapping.HostPort, mapping.Protocol)
+		if other, ok := hostPorts[key]; ok {
+			return status.Errorf(codes.FailedPrecondition, "host port %s is used by both %s and the ingress controller", key, other)
+		}
+		hostPorts[key] = "the ingress controller"
+	}
+
+	for i, node := range config.Nodes.nodes() {
+		for _, mapping := range node.ExtraPortMappings {
+			key := fmt.Sprintf("%d/%s", mapping.HostPort, mapping.protocol())
+			if other, ok := hostPorts[key]; ok {
+				return status.Errorf(codes.FailedPrecondition, "host port %s is used by both %s and node %d", key, other, i)
+			}
+			hostPorts[key] = fmt.Sprintf("node %d", i)
+		}
+	}
+
+	for _, app := range config.Applications {
+		for _, port := range app.Ports {
+			if port.HostPort != 0 {
+				key := fmt.Sprintf("%d/%s", port.HostPort, port.protocol())
+				if other, ok := hostPorts[key]; ok {
+					return status.Errorf(codes.FailedPrecondition, "host port %s is used by both %s and %s", key, other, app.Name)
+				}
+				hostPorts[key] = app.Name
+			}
+
+			if nodePort := port.nodePort(); nodePort != 0 {
+				key := fmt.Sprintf("%d/%s", nodePort, port.protocol())
+				if other, ok := nodePorts[key]; ok {
+					return status.Errorf(codes.FailedPrecondition, "node port %s is used by both %s and %s", key, other, app.Name)
+				}
+				nodePorts[key] = app.Name
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateResources(app Application) error {
+	requirements, err := app.Resources.requirements()
+	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, "%v", err)
+	}
+
+	for name, limit := range requirements.Limits {
+		if limit.Sign() <= 0 {
+			return status.Errorf(codes.FailedPrecondition, "%s limit must be greater than 0", name)
+		}
+
+		if request, ok := requirements.Requests[name]; ok && limit.Cmp(request) < 0 {
+			return status.Errorf(codes.FailedPrecondition, "%s limit %s is below its request %s", name, limit.String(), request.String())
+		}
+	}
+
+	for name, request := range requirements.Requests {
+		if request.Sign() < 0 {
+			return status.Errorf(codes.FailedPrecondition, "%s request cannot be negative", name)
+		}
+	}
+
+	return nil
+}
+
+func validateProbes(app Application) error {
+	probes := map[string]*Probe{
+		"liveness":  app.LivenessProbe,
+		"readiness": app.ReadinessProbe,
+		"startup":   app.StartupProbe,
+	}
+
+	for name, probe := range probes {
+		if probe == nil {
+			continue
+		}
+
+		if err := validateProbe(app, probe); err != nil {
+			return fmt.Errorf("%s probe is invalid: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func validateProbe(app Application, probe *Probe) error {
+	ports := []int{}
+	if probe.HTTP != nil {
+		ports = append(ports, app.probePort(probe.HTTP.Port))
+		if !strings.HasPrefix(probe.HTTP.Path, "/") {
+			return status.Errorf(codes.FailedPrecondition, "http path %q must start with /", probe.HTTP.Path)
+		}
+	}
+	if probe.TCP != nil {
+		ports = append(ports, app.probePort(probe.TCP.Port))
+	}
+
+	handlers := len(ports)
+	if len(probe.Exec) > 0 {
+		handlers++
+	}
+	if handlers != 1 {
+		return status.Errorf(codes.FailedPrecondition, "exactly one of http, tcp or exec must be specified")
+	}
+
+	for _, port := range ports {
+		if port == 0 {
+			return status.Errorf(codes.FailedPrecondition, "a port must be specified when the application has no ports")
+		} else if port < 1 || port > 65535 {
+			return status.Errorf(codes.FailedPrecondition, "port %d must be between 1 and 65535", port)
+		}
+	}
+
+	if probe.InitialDelaySeconds < 0 || probe.PeriodSeconds < 0 || probe.TimeoutSeconds < 0 || probe.FailureThreshold < 0 {
+		return status.Errorf(codes.FailedPrecondition, "probe timings cannot be negative")
+	}
+
+	return nil
+}
+
+// checks scheduling settings, and that the application's pods fit on at least one declared node
+func validateScheduling(app Application, config *ClusterConfig) error {
+	for key, value := range app.NodeSelector {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return status.Errorf(codes.FailedPrecondition, "node selector key %q is invalid: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return status.Errorf(codes.FailedPrecondition, "node selector value %q is invalid: %s", value, strings.Join(errs, ", "))
+		}
+	}
+
+	for _, toleration := range app.Tolerations {
+		switch apiv1.TolerationOperator(toleration.Operator) {
+		case "", apiv1.TolerationOpEqual:
+			if toleration.Key == "" {
+				return status.Errorf(codes.FailedPrecondition, "a toleration without a key must use the Exists operator")
+			}
+		case apiv1.TolerationOpExists:
+			if toleration.Value != "" {
+				return status.Errorf(codes.FailedPrecondition, "a toleration with the Exists operator cannot have a value")
+			}
+		default:
+			return status.Errorf(codes.FailedPrecondition, "toleration operator must be one of [Equal, Exists]")
+		}
+
+		switch apiv1.TaintEffect(toleration.Effect) {
+		case "", apiv1.TaintEffectNoSchedule, apiv1.TaintEffectPreferNoSchedule, apiv1.TaintEffectNoExecute:
+		default:
+			return status.Errorf(codes.FailedPrecondition, "toleration effect must be one of [NoSchedule, PreferNoSchedule, NoExecute]")
+		}
+	}
+
+	switch app.AntiAffinity {
+	case "", preferredAntiAffinity, requiredAntiAffinity:
+	default:
+		return status.Errorf(codes.FailedPrecondition, "anti affinity must be one of [%s, %s]", preferredAntiAffinity, requiredAntiAffinity)
+	}
+
+	if len(app.NodeSelector) == 0 {
+		return nil
+	}
+
+	selected := false
+	nodeLabels, nodeTaints := config.nodeLabelsAndTaints(app.cluster)
+	for i, labels := range nodeLabels {
+		matches := true
+		for key, value := range app.NodeSelector {
+			if nodeValue, ok := labels[key]; !ok || nodeValue != value {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+
+		selected = true
+		if tolerates(app.Tolerations, nodeTaints[i]) {
+			return nil
+		}
+	}
+
+	if selected {
+		return status.Errorf(codes.FailedPrecondition, "every node matching the node selector has a taint the application does not tolerate")
+	}
+	return status.Errorf(codes.FailedPrecondition, "the node selector does not match any node declared in the cluster configuration")
+}
+
+func validateConfigData(app Application) error {
+	// keys of the config maps and secrets declared by the application
+	keys := map[string]map[string]bool{}
+	for _, configMap := range app.ConfigMaps {
+		if configMap.Name == "" {
+			return status.Errorf(codes.FailedPrecondition, "config map name must not be empty")
+		}
+
+		if err := followsNamingConvention(configMap.Name); err != nil {
+			return fmt.Errorf("config map name does not follow naming convention: %w", err)
+		}
+
+		dataKeys := []string{}
+		for key := range configMap.Data {
+			dataKeys = append(dataKeys, key)
+		}
+
+		configMapKeys, err := validateKeys(app, dataKeys, configMap.Files, nil)
+		if err != nil {
+			return fmt.Errorf("config map %s is invalid: %w", configMap.Name, err)
+		}
+		keys[configMapKind+"/"+configMap.Name] = configMapKeys
+
+		if err := validateMountPath(configMap.MountPath); err != nil {
+			return err
+		}
+	}
+
+	for _, secret := range app.Secrets {
+		if secret.Name == "" {
+			return status.Errorf(codes.FailedPrecondition, "secret name must not be empty")
+		}
+
+		if err := followsNamingConvention(secret.Name); err != nil {
+			return fmt.Errorf("secret name does not follow naming convention: %w", err)
+		}
+
+		secretKeys, err := validateKeys(app, nil, secret.Files, secret.Env)
+		if err != nil {
+			return fmt.Errorf("secret %s is invalid: %w", secret.Name, err)
+		}
+		keys[secretKind+"/"+secret.Name] = secretKeys
+
+		if err := validateMountPath(secret.MountPath); err != nil {
+			return err
+		}
+	}
+
+	for _, variable := range app.Env {
+		if errs := validation.IsEnvVarName(variable.Name); len(errs) > 0 {
+			return status.Errorf(codes.FailedPrecondition, "environment variable name %q is invalid: %s", variable.Name, strings.Join(errs, ", "))
+		}
+
+		source := ""
+		switch {
+		case variable.ConfigMap != "" && variable.Secret != "":
+			return status.Errorf(codes.FailedPrecondition, "environment variable %s cannot reference both a config map and a secret", variable.Name)
+		case variable.ConfigMap != "":
+			source = configMapKind + "/" + variable.ConfigMap
+		case variable.Secret != "":
+			source = secretKind + "/" + variable.Secret
+		}
+
+		if source == "" {
+			if variable.Key != "" {
+				return status.Errorf(codes.FailedPrecondition, "environment variable %s sets a key without a config map or secret", variable.Name)
+			}
+			continue
+		}
+
+		if variable.Value != "" {
+			return status.Errorf(codes.FailedPrecondition, "environment variable %s cannot set both a value and a reference", variable.Name)
+		} else if variable.Key == "" {
+			return status.Errorf(codes.FailedPrecondition, "environment variable %s must set the key it references", variable.Name)
+		}
+
+		// references to config maps and secrets declared on the application must name one of their keys
+		if declared, ok := keys[source]; ok && !declared[variable.Key] {
+			return status.Errorf(codes.FailedPrecondition, "environment variable %s references key %s, which %s does not have", variable.Name, variable.Key, source)
+		}
+	}
+
+	return nil
+}
+
+// checks that data keys, files and environment variables make valid, unique keys, returning the keys
+func validateKeys(app Application, dataKeys []string, files []string, env []string) (map[string]bool, error) {
+	keys := map[string]bool{}
+	add := func(key string) error {
+		if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+			return status.Errorf(codes.FailedPrecondition, "key %q is invalid: %s", key, strings.Join(errs, ", "))
+		} else if keys[key] {
+			return status.Errorf(codes.FailedPrecondition, "key %s is used more than once", key)
+		}
+		keys[key] = true
+		return nil
+	}
+
+	for _, key := range dataKeys {
+		if err := add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, file := range files {
+		info, err := os.Stat(app.path(file))
+		if err != nil {
+			return nil, status.Errorf(codes.FailedPrecondition, "error reading file %s: %v", file, err)
+		} else if !info.Mode().IsRegular() {
+			return nil, status.Errorf(codes.FailedPrecondition, "file %s is not a regular file", file)
+		}
+
+		if err := add(filepath.Base(file)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range env {
+		if _, ok := os.LookupEnv(name); !ok {
+			return nil, status.Errorf(codes.FailedPrecondition, "environment variable %s is not set", name)
+		}
+
+		if err := add(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+func validateMountPath(mountPath string) error {
+	if mountPath != "" && !path.IsAbs(mountPath) {
+		return status.Errorf(codes.FailedPrecondition, "mount path %s must be absolute", mountPath)
+	}
+	return nil
+}
+
+// checks that no two config maps or secrets in the same namespace share a name
+func validateConfigNames(apps []Application) error {
+	names := map[string]string{}
+	claim := func(app Application, kind string, name string) error {
+		key := resourceKey(kind, app.Namespace, name)
+		if other, ok := names[key]; ok {
+			return status.Errorf(codes.FailedPrecondition, "%s %s/%s is declared by both %s and %s", kind, app.Namespace, name, other, app.Name)
+		}
+		names[key] = app.Name
+		return nil
+	}
+
+	for _, app := range apps {
+		for _, configMap := range app.ConfigMaps {
+			if err := claim(app, configMapKind, configMap.Name); err != nil {
+				return err
+			}
+		}
+
+		for _, secret := range app.Secrets {
+			if err := claim(app, secretKind, secret.Name); err != nil {
+				return err
+			}
+		}
+
+		if app.Ingress != nil {
+			if err := claim(app, secretKind, app.Name+tlsSecretSuffix); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateIngress(app Application, ingress *IngressConfig) error {
+	if app.Ingress == nil {
+		return nil
+	}
+
+	if !ingress.enabled() {
+		return status.Errorf(codes.FailedPrecondition, "ingress must be enabled on the cluster for applications to declare ingress hosts")
+	}
+
+	if len(app.Ports) == 0 || app.Type == jobType || app.Type == cronjobType {
+		return status.Errorf(codes.FailedPrecondition, "ingress can only be declared for applications with ports")
+	}
+
+	if errs := validation.IsDNS1123Subdomain(app.Ingress.Host); len(errs) > 0 {
+		return status.Errorf(codes.FailedPrecondition, "ingress host %q is invalid: %s", app.Ingress.Host, strings.Join(errs, ", "))
+	}
+
+	for _, path := range app.Ingress.paths() {
+		if !strings.HasPrefix(path.Path, "/") {
+			return status.Errorf(codes.FailedPrecondition, "ingress path %q must start with /", path.Path)
+		}
+
+		switch networkingv1.PathType(path.PathType) {
+		case "", networkingv1.PathTypePrefix, networkingv1.PathTypeExact, networkingv1.PathTypeImplementationSpecific:
+		default:
+			return status.Errorf(codes.FailedPrecondition, "ingress path type must be one of [Prefix, Exact, ImplementationSpecific]")
+		}
+
+		found := false
+		for _, port := range app.Ports {
+			if port.ContainerPort == app.ingressPort(path) {
+				found = true
+			}
+		}
+		if !found {
+			return status.Errorf(codes.FailedPrecondition, "ingress path %s routes to port %d, which is not one of the application's ports", path.Path, path.Port)
+		}
+	}
+
+	return nil
+}
+
+// checks that no two applications claim the same ingress host and path
+func validateIngressRoutes(apps []Application) error {
+	routes := map[string]string{}
+	for _, app := range apps {
+		if app.Ingress == nil {
+			continue
+		}
+
+		for _, path := range app.Ingress.paths() {
+			route := app.Ingress.Host + path.Path
+			if other, ok := routes[route]; ok {
+				return status.Errorf(codes.FailedPrecondition, "ingress route %s is used by both %s and %s", route, other, app.Name)
+			}
+			routes[route] = app.Name
+		}
+	}
+
+	return nil
+}
+
+func validateDependencies(app Application, apps []Application) error {
+	for _, dependency := range app.DependsOn {
+		found := false
+		for _, other := range apps {
+			if other.Name != dependency {
+				continue
+			}
+
+			found = true
+			if other.Type != jobType {
+				return status.Errorf(codes.FailedPrecondition, "dependency %s must be a job", dependency)
+			}
+		}
+
+		if !found {
+			return status.Errorf(codes.FailedPrecondition, "dependency %s is not an application in the cluster config", dependency)
+		}
+	}
+
+	return nil
+}
+
+func validateStorage(app Application) error {
+	if app.Storage == nil {
+		return nil
+	}
+
+	if app.Type != statefulsetType {
+		return status.Errorf(codes.FailedPrecondition, "storage can only be specified for statefulsets")
+	}
+
+	if app.Storage.Size == "" {
+		return status.Errorf(codes.FailedPrecondition, "storage size must not be empty")
+	}
+
+	if _, err := k8sresource.ParseQuantity(app.Storage.Size); err != nil {
+		return status.Errorf(codes.FailedPrecondition, "storage size %s is not a valid quantity: %v", app.Storage.Size, err)
+	}
+
+	if app.Storage.MountPath != "" && !path.IsAbs(app.Storage.MountPath) {
+		return status.Errorf(codes.FailedPrecondition, "storage mount path %s must be absolute", app.Storage.MountPath)
+	}
+
+	return nil
+}
+
 func followsNamingConvention(s string) error {
+	if s == "" {
+		return status.Errorf(codes.FailedPrecondition, "must not be empty")
+	}
+
 	// only contains letters, numbers, and hyphens
 	if !regexp.MustCompile(`^[a-z0-9-]*$`).MatchString(s) {
 		return status.Errorf(codes.FailedPrecondition, "only lowercase letters, numbers, and hyphens are allowed")
@@ -0,0 +1,105 @@
+package cluster
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidateConfigDataEmptyName(t *testing.T) {
+	tests := []struct {
+		name string
+		app  Application
+	}{
+		{
+			name: "config map",
+			app:  Application{Name: "app", ConfigMaps: []ConfigMap{{Data: map[string]string{"key": "value"}}}},
+		},
+		{
+			name: "config map mounted as a volume",
+			app:  Application{Name: "app", ConfigMaps: []ConfigMap{{Data: map[string]string{"key": "value"}, MountPath: "/etc/app"}}},
+		},
+		{
+			name: "secret",
+			app:  Application{Name: "app", Secrets: []Secret{{Env: []string{"HOME"}}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateConfigData(test.app)
+			if status.Code(err) != codes.FailedPrecondition {
+				t.Fatalf("expected a FailedPrecondition error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFollowsNamingConvention(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{name: "", valid: false},
+		{name: "web", valid: true},
+		{name: "web-2", valid: true},
+		{name: "2web", valid: false},
+		{name: "web-", valid: false},
+		{name: "Web", valid: false},
+	}
+
+	for _, test := range tests {
+		err := followsNamingConvention(test.name)
+		if test.valid && err != nil {
+			t.Errorf("expected %q to be valid, got %v", test.name, err)
+		} else if !test.valid && status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("expected %q to be rejected with a FailedPrecondition error, got %v", test.name, err)
+		}
+	}
+}
+
+func TestValidateEmptyApplicationName(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "cluster.yaml")
+	err := os.WriteFile(configFile, []byte(`name: dev
+applications:
+  - name: ""
+    namespace: default
+    image: nginx:1.25
+    replicas: 1
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Cluster{ConfigFile: configFile}
+	if err := c.Validate(context.Background()); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected a FailedPrecondition error, got %v", err)
+	}
+}
+
+func TestValidatePortNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		port  Port
+		valid bool
+	}{
+		{name: "unnamed port", port: Port{ContainerPort: 80}, valid: true},
+		{name: "named port", port: Port{Name: "http", ContainerPort: 80}, valid: true},
+		{name: "invalid name", port: Port{Name: "-http", ContainerPort: 80}, valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validatePorts(Application{Name: "web", Ports: []Port{test.port}})
+			if test.valid && err != nil {
+				t.Fatalf("expected the port to be valid, got %v", err)
+			} else if !test.valid && status.Code(err) != codes.FailedPrecondition {
+				t.Fatalf("expected a FailedPrecondition error, got %v", err)
+			}
+		})
+	}
+}
@@ -2,14 +2,48 @@ package cmd
 
 import (
 	"context"
+	"os"
+	"os/signal"
 	"skillet/skillet-kind/cluster"
+	"syscall"
 
 	"github.com/urfave/cli/v3"
 )
 
+var (
+	pruneFlag = &cli.BoolFlag{
+		Name:  "prune",
+		Usage: "Delete applications and namespaces created by skillet that are no longer in the configuration",
+	}
+	dryRunFlag = &cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "Print the planned changes without making them",
+	}
+	timeoutFlag = &cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "How long to wait for each application to roll out or job to complete",
+		Value: cluster.DefaultTimeout,
+	}
+	chartsFileFlag = &cli.StringFlag{
+		Name:  "charts-file",
+		Usage: "Path to a file listing the default helm charts, instead of ~/.skillet/charts.yaml or the built-in list",
+	}
+	setFlag = &cli.StringSliceFlag{
+		Name:  "set",
+		Usage: "Set a helm value of a release as RELEASE:KEY=VALUE, over the values in the configuration. Can be repeated",
+	}
+	outputFlag = &cli.StringFlag{
+		Name:  "output",
+		Usage: "Format of the printed plan, one of [text, json]",
+		Value: cluster.TextOutput,
+	}
+)
+
 var CreateCommand = &cli.Command{
 	Name:  "create",
 	Usage: "create a new cluster",
+	// --set values are split on commas by helm itself
+	DisableSliceFlagSeparator: true,
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:  "name",
@@ -19,14 +53,125 @@ var CreateCommand = &cli.Command{
 			Name:  "file",
 			Usage: "Path to the file containing the cluster configuration",
 		},
+		dryRunFlag,
+		outputFlag,
+		timeoutFlag,
+		&cli.BoolFlag{
+			Name:  "keep-on-failure",
+			Usage: "Keep the cluster for debugging if creating it fails, instead of deleting it",
+		},
+		&cli.BoolFlag{
+			Name:  "resume",
+			Usage: "Continue a failed creation of a kept cluster from the phase that failed",
+		},
+		chartsFileFlag,
+		setFlag,
 	},
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		cluster := cluster.NewCluster(cmd.String("name"), cmd.String("file"))
+		cluster.ChartsFile = cmd.String("charts-file")
+		cluster.SetValues = cmd.StringSlice("set")
+		cluster.DryRun = cmd.Bool("dry-run")
+		cluster.Output = cmd.String("output")
+		cluster.Timeout = cmd.Duration("timeout")
+		cluster.KeepOnFailure = cmd.Bool("keep-on-failure")
+		cluster.Resume = cmd.Bool("resume")
 		err := cluster.Create(ctx)
 		return err
 	},
 }
 
+var ApplyCommand = &cli.Command{
+	Name:  "apply",
+	Usage: "reconcile an existing cluster against its configuration",
+	// --set values are split on commas by helm itself
+	DisableSliceFlagSeparator: true,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "file",
+			Usage: "Path to the file containing the cluster configuration",
+		},
+		pruneFlag,
+		dryRunFlag,
+		outputFlag,
+		timeoutFlag,
+		chartsFileFlag,
+		setFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		cluster := cluster.NewCluster("", cmd.String("file"))
+		cluster.ChartsFile = cmd.String("charts-file")
+		cluster.SetValues = cmd.StringSlice("set")
+		cluster.Prune = cmd.Bool("prune")
+		cluster.DryRun = cmd.Bool("dry-run")
+		cluster.Output = cmd.String("output")
+		cluster.Timeout = cmd.Duration("timeout")
+		err := cluster.Apply(ctx)
+		return err
+	},
+}
+
+var PlanCommand = &cli.Command{
+	Name:  "plan",
+	Usage: "show what create or apply would change",
+	// --set values are split on commas by helm itself
+	DisableSliceFlagSeparator: true,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "name",
+			Usage: "The name of the cluster",
+		},
+		&cli.StringFlag{
+			Name:  "file",
+			Usage: "Path to the file containing the cluster configuration",
+		},
+		pruneFlag,
+		outputFlag,
+		chartsFileFlag,
+		setFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		cluster := cluster.NewCluster(cmd.String("name"), cmd.String("file"))
+		cluster.ChartsFile = cmd.String("charts-file")
+		cluster.SetValues = cmd.StringSlice("set")
+		cluster.Prune = cmd.Bool("prune")
+		plan, err := cluster.Plan(ctx)
+		if err != nil {
+			return err
+		}
+
+		return plan.Write(os.Stdout, cmd.String("output"))
+	},
+}
+
+var DevCommand = &cli.Command{
+	Name:  "dev",
+	Usage: "watch a cluster's configuration and build contexts, rebuilding and redeploying applications as they change",
+	// --set values are split on commas by helm itself
+	DisableSliceFlagSeparator: true,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "file",
+			Usage: "Path to the file containing the cluster configuration",
+		},
+		timeoutFlag,
+		chartsFileFlag,
+		setFlag,
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		// stop watching on Ctrl-C, leaving the cluster running
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		cluster := cluster.NewCluster("", cmd.String("file"))
+		cluster.ChartsFile = cmd.String("charts-file")
+		cluster.SetValues = cmd.StringSlice("set")
+		cluster.Timeout = cmd.Duration("timeout")
+		err := cluster.Dev(ctx)
+		return err
+	},
+}
+
 var DeleteCommand = &cli.Command{
 	Name:  "delete",
 	Usage: "delete a cluster",
@@ -35,26 +180,100 @@ var DeleteCommand = &cli.Command{
 			Name:  "name",
 			Usage: "The name of the cluster to be deleted",
 		},
+		&cli.StringFlag{
+			Name:  "file",
+			Usage: "Path to the file containing the cluster configuration",
+		},
+		&cli.BoolFlag{
+			Name:  "remove-registry",
+			Usage: "Also remove the cluster's local registry container and the images pushed to it, unless other clusters still use it",
+		},
+		&cli.BoolFlag{
+			Name:  "force-remove-registry",
+			Usage: "Remove the cluster's local registry container even if other clusters still use it",
+		},
 	},
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		cluster := cluster.NewCluster(cmd.String("name"), cmd.String("file"))
+		cluster.RemoveRegistry = cmd.Bool("remove-registry") || cmd.Bool("force-remove-registry")
+		cluster.ForceRemoveRegistry = cmd.Bool("force-remove-registry")
 		err := cluster.Delete(ctx)
 		return err
 	},
 }
 
+var LoadImageCommand = &cli.Command{
+	Name:      "load-image",
+	Usage:     "load images from the host's docker daemon into every node of a cluster",
+	ArgsUsage: "IMAGE [IMAGE...]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "name",
+			Usage: "The name of the cluster",
+		},
+		&cli.StringFlag{
+			Name:  "file",
+			Usage: "Path to the file containing the cluster configuration",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		cluster := cluster.NewCluster(cmd.String("name"), cmd.String("file"))
+		err := cluster.LoadImages(ctx, cmd.Args().Slice())
+		return err
+	},
+}
+
 var ValidateCommand = &cli.Command{
 	Name:  "validate",
 	Usage: "validate a cluster configuration",
+	// --set values are split on commas by helm itself
+	DisableSliceFlagSeparator: true,
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:  "file",
 			Usage: "The path to the file where the cluster configuration is stored",
 		},
+		chartsFileFlag,
+		setFlag,
 	},
 	Action: func(ctx context.Context, cmd *cli.Command) error {
 		cluster := cluster.NewCluster("", cmd.String("file"))
+		cluster.ChartsFile = cmd.String("charts-file")
+		cluster.SetValues = cmd.StringSlice("set")
 		err := cluster.Validate(ctx)
 		return err
 	},
 }
+
+var CACommand = &cli.Command{
+	Name:  "ca",
+	Usage: "print or export the certificate authority that signs a cluster's ingress hosts",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "name",
+			Usage: "The name of the cluster",
+		},
+		&cli.StringFlag{
+			Name:  "file",
+			Usage: "Path to the file containing the cluster configuration",
+		},
+		&cli.StringFlag{
+			Name:  "export",
+			Usage: "Path to write the CA bundle to instead of printing it",
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		cluster := cluster.NewCluster(cmd.String("name"), cmd.String("file"))
+		bundle, err := cluster.CABundle()
+		if err != nil {
+			return err
+		}
+
+		if path := cmd.String("export"); path != "" {
+			return os.WriteFile(path, bundle, 0o644)
+		}
+
+		_, err = os.Stdout.Write(bundle)
+		return err
+	},
+}
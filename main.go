@@ -16,8 +16,13 @@ func main() {
 		Usage: "CLI tool to deploy kind clusters",
 		Commands: []*cli.Command{
 			cmd.CreateCommand,
+			cmd.ApplyCommand,
+			cmd.PlanCommand,
+			cmd.DevCommand,
 			cmd.DeleteCommand,
+			cmd.LoadImageCommand,
 			cmd.ValidateCommand,
+			cmd.CACommand,
 		},
 	}
 